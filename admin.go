@@ -0,0 +1,197 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+
+    "github.com/gorilla/mux"
+    "github.com/tobyjsullivan/ues-sdk/event"
+    "github.com/tobyjsullivan/event-log-reader/cache"
+    "github.com/tobyjsullivan/event-log-reader/source"
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+)
+
+// The admin routes are served on their own listener (ADMIN_PORT) so they are never
+// exposed on the public port.
+func runAdmin(addr string) {
+    logger.Println("Admin listening on", addr)
+    err := http.ListenAndServe(addr, buildAdminRoutes())
+    if err != nil {
+        logger.Println("Admin listener stopped.", err.Error())
+    }
+}
+
+func buildAdminRoutes() http.Handler {
+    r := mux.NewRouter()
+    r.HandleFunc("/admin/cache", cacheStatsHandler).Methods("GET")
+    r.HandleFunc("/admin/cache/memory", flushMemoryCacheHandler).Methods("DELETE")
+    r.HandleFunc("/admin/cache/events/{eventId}", evictEventHandler).Methods("DELETE")
+    r.HandleFunc("/admin/cache/warm/{logId}", warmLogHandler).Methods("POST")
+    r.HandleFunc("/admin/upstream", upstreamStatsHandler).Methods("GET")
+    r.HandleFunc("/admin/verify", verifyReportsHandler).Methods("GET")
+
+    return r
+}
+
+func cacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+    tiers := eventStore.Tiers()
+    out := make([]*tierStats, len(tiers))
+    for i, t := range tiers {
+        stats := &tierStats{
+            Name: t.Name(),
+            Stats: t.Stats(),
+        }
+        if h, ok := t.(interface{ Healthy() bool }); ok {
+            healthy := h.Healthy()
+            stats.Healthy = &healthy
+        }
+        switch t := t.(type) {
+        case *cache.EventCache:
+            stats.Size = t.Len()
+            stats.MaxSize = t.MaxSize()
+        case *cache.DiskTier:
+            stats.Size = t.Len()
+            stats.Bytes = t.Bytes()
+            stats.MaxBytes = t.MaxBytes()
+        }
+        out[i] = stats
+    }
+
+    writeJson(w, &cacheStatsResponse{
+        Tiers: out,
+        Source: eventStore.SourceStats(),
+        WriteQueue: eventStore.WriteQueueStats(),
+        Integrity: eventStore.IntegrityStats(),
+    })
+}
+
+func flushMemoryCacheHandler(w http.ResponseWriter, r *http.Request) {
+    t, ok := eventStore.Tier("memory")
+    if !ok {
+        http.Error(w, "The memory cache tier is not configured.", http.StatusNotFound)
+        return
+    }
+    n := t.(*cache.EventCache).Flush()
+
+    writeJson(w, &flushCacheResponse{
+        Evicted: n,
+    })
+}
+
+func evictEventHandler(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    id := event.EventID{}
+    err := id.Parse(vars["eventId"])
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    removed, errs := eventStore.Remove(id)
+    resp := &evictEventResponse{
+        EventID: id.String(),
+        Tiers: removed,
+    }
+    if len(errs) > 0 {
+        // Report the tiers that did evict the event along with those that failed.
+        resp.Errors = make(map[string]string, len(errs))
+        for name, err := range errs {
+            resp.Errors[name] = err.Error()
+        }
+        w.WriteHeader(http.StatusInternalServerError)
+    }
+
+    writeJson(w, resp)
+}
+
+func warmLogHandler(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    logId := eventLog.LogID{}
+    err := logId.Parse(vars["logId"])
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    headEventId, err := getLogHead(r.Context(), logId)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+
+    events, err := getLogHistory(r.Context(), logId, headEventId, event.EventID{})
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+
+    writeJson(w, &warmLogResponse{
+        LogID: logId.String(),
+        Head: headEventId.String(),
+        Events: len(events),
+    })
+}
+
+func upstreamStatsHandler(w http.ResponseWriter, r *http.Request) {
+    switch u := upstream.(type) {
+    case *source.Pool:
+        writeJson(w, u.Stats())
+    case source.Endpoint:
+        writeJson(w, &source.PoolStats{
+            Endpoints: []source.EndpointStats{{Name: u.Name(), Healthy: u.Healthy()}},
+        })
+    default:
+        http.Error(w, "Not Found", http.StatusNotFound)
+    }
+}
+
+func verifyReportsHandler(w http.ResponseWriter, r *http.Request) {
+    if verifyJob == nil {
+        http.Error(w, "The verification job is not enabled.", http.StatusNotFound)
+        return
+    }
+
+    writeJson(w, verifyJob.Reports())
+}
+
+func writeJson(w http.ResponseWriter, data interface{}) {
+    encoder := json.NewEncoder(w)
+    err := encoder.Encode(&jsonResponse{Data: data})
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+}
+
+type cacheStatsResponse struct {
+    Tiers []*tierStats `json:"tiers"`
+    Source cache.SourceStats `json:"source"`
+    WriteQueue cache.WriteQueueStats `json:"writeQueue"`
+    Integrity cache.IntegrityStats `json:"integrity"`
+}
+
+type tierStats struct {
+    Name string `json:"name"`
+    cache.Stats
+    Size int `json:"size,omitempty"`
+    MaxSize int `json:"maxSize,omitempty"`
+    Bytes int64 `json:"bytes,omitempty"`
+    MaxBytes int64 `json:"maxBytes,omitempty"`
+    Healthy *bool `json:"healthy,omitempty"`
+}
+
+type flushCacheResponse struct {
+    Evicted int `json:"evicted"`
+}
+
+type evictEventResponse struct {
+    EventID string `json:"eventId"`
+    Tiers map[string]bool `json:"tiers"`
+    Errors map[string]string `json:"errors,omitempty"`
+}
+
+type warmLogResponse struct {
+    LogID string `json:"logId"`
+    Head string `json:"head"`
+    Events int `json:"events"`
+}
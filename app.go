@@ -1,81 +1,175 @@
 package main
 
 import (
+    "context"
     "os"
 
     "fmt"
+    "net"
     "net/http"
 
     "github.com/urfave/negroni"
     "github.com/gorilla/mux"
     "log"
-    "database/sql"
+    "time"
 
-    _ "github.com/lib/pq"
     eventLog "github.com/tobyjsullivan/event-log-reader/log"
     "encoding/json"
-    "encoding/base64"
-    "github.com/tobyjsullivan/ues-sdk/event/reader"
     "github.com/tobyjsullivan/ues-sdk/event"
     "github.com/tobyjsullivan/event-log-reader/cache"
-    "github.com/go-redis/redis"
+    "github.com/tobyjsullivan/event-log-reader/chain"
+    "github.com/tobyjsullivan/event-log-reader/source"
 )
 
 const (
     CACHE_MAX_KEYS = 50000
+    CACHE_WRITE_WORKERS = 4
+    CACHE_FETCH_CONCURRENCY = 8
+    DEFAULT_CACHE_WRITE_QUEUE_SIZE = 10000
+    DEFAULT_REQUEST_TIMEOUT = 30 * time.Second
+    DEFAULT_UPSTREAM_TIMEOUT = 5 * time.Second
 )
 
 var (
     logger     *log.Logger
-    db         *sql.DB
-    eventReader *reader.EventReader
-    eventCache *cache.EventCache
-    redisClient *redis.Client
+    heads eventLog.HeadStore
+    upstream source.EventSource
+    requestTimeout time.Duration
+    eventStore *cache.Store
+    links chain.LinkIndex
+    skips *chain.SkipIndex
+    segments *chain.Segments
+    logIndex *chain.LogIndex
 )
 
 func init() {
     logger = log.New(os.Stdout, "[svc] ", 0)
 
-    pgHostname := os.Getenv("PG_HOSTNAME")
-    pgUsername := os.Getenv("PG_USERNAME")
-    pgPassword := os.Getenv("PG_PASSWORD")
-    pgDatabase := os.Getenv("PG_DATABASE")
+    var err error
+    heads, err = buildHeadStore(os.Getenv("HEAD_STORE"))
+    if err != nil {
+        logger.Println("Error initializing log head store.", err.Error())
+        panic(err.Error())
+    }
 
-    dbConnOpts := fmt.Sprintf("host='%s' user='%s' dbname='%s' password='%s' sslmode=disable",
-        pgHostname, pgUsername, pgDatabase, pgPassword)
+    requestTimeout, err = envDuration("REQUEST_TIMEOUT", DEFAULT_REQUEST_TIMEOUT)
+    if err != nil {
+        logger.Println("Error reading request timeout.", err.Error())
+        panic(err.Error())
+    }
 
-    logger.Println("Connecting to DB...")
-    var err error
-    db, err = sql.Open("postgres", dbConnOpts)
+    upstreamTimeout, err := envDuration("UPSTREAM_TIMEOUT", DEFAULT_UPSTREAM_TIMEOUT)
     if err != nil {
-        logger.Println("Error initializing connection to Postgres DB.", err.Error())
+        logger.Println("Error initializing Event Reader API.", err.Error())
         panic(err.Error())
     }
 
-    eventReader, err = reader.New(&reader.EventReaderConfig{
-        ServiceUrl: os.Getenv("EVENT_READER_API"),
-    })
+    upstreamRetries, err := envInt64("UPSTREAM_RETRIES", source.DEFAULT_HTTP_RETRIES)
     if err != nil {
         logger.Println("Error initializing Event Reader API.", err.Error())
         panic(err.Error())
     }
 
-    eventCache = cache.New(CACHE_MAX_KEYS)
+    upstreamMaxIdleConns, err := envInt64("UPSTREAM_MAX_IDLE_CONNS", source.DEFAULT_HTTP_MAX_IDLE_CONNS)
+    if err != nil {
+        logger.Println("Error initializing Event Reader API.", err.Error())
+        panic(err.Error())
+    }
 
-    redisClient = redis.NewClient(&redis.Options{
-        Addr: fmt.Sprintf("%s:%s", os.Getenv("REDIS_HOSTNAME"), os.Getenv("REDIS_PORT")),
-        Password: os.Getenv("REDIS_PASSWORD"),
-        DB: 0,
+    upstreamBreakerThreshold, err := envInt64("UPSTREAM_BREAKER_THRESHOLD", source.DEFAULT_BREAKER_THRESHOLD)
+    if err != nil {
+        logger.Println("Error initializing Event Reader API.", err.Error())
+        panic(err.Error())
+    }
+
+    upstreamBreakerCooldown, err := envDuration("UPSTREAM_BREAKER_COOLDOWN", source.DEFAULT_BREAKER_COOLDOWN)
+    if err != nil {
+        logger.Println("Error initializing Event Reader API.", err.Error())
+        panic(err.Error())
+    }
+
+    upstream, err = buildEventSource(os.Getenv("EVENT_SOURCE"), source.HTTPConfig{
+        Timeout: upstreamTimeout,
+        Retries: int(upstreamRetries),
+        MaxIdleConns: int(upstreamMaxIdleConns),
+        BreakerThreshold: int(upstreamBreakerThreshold),
+        BreakerCooldown: upstreamBreakerCooldown,
     })
+    if err != nil {
+        logger.Println("Error initializing Event Reader API.", err.Error())
+        panic(err.Error())
+    }
+
+    signingKeyFile := os.Getenv("SIGNING_KEY_FILE")
+    if signingKeyFile != "" {
+        signer, err = loadSigner(signingKeyFile)
+        if err != nil {
+            logger.Println("Error loading signing key.", err.Error())
+            panic(err.Error())
+        }
+    }
+
+    tiers, err := buildCacheTiers(os.Getenv("CACHE_TIERS"))
+    if err != nil {
+        logger.Println("Error initializing cache tiers.", err.Error())
+        panic(err.Error())
+    }
+
+    policy, err := cache.ParseWritePolicy(os.Getenv("CACHE_WRITE_POLICY"))
+    if err != nil {
+        logger.Println("Error initializing cache tiers.", err.Error())
+        panic(err.Error())
+    }
+
+    queueSize, err := envInt64("CACHE_WRITE_QUEUE_SIZE", DEFAULT_CACHE_WRITE_QUEUE_SIZE)
+    if err != nil {
+        logger.Println("Error initializing cache tiers.", err.Error())
+        panic(err.Error())
+    }
+
+    eventStore = cache.NewStore(upstream, &cache.StoreOptions{
+        Policy: policy,
+        WriteQueueSize: int(queueSize),
+        WriteWorkers: CACHE_WRITE_WORKERS,
+        FetchConcurrency: CACHE_FETCH_CONCURRENCY,
+    }, tiers...)
 
-    pong, err := redisClient.Ping().Result()
-    logger.Println("Pong result:", pong, err)
+    links, err = buildLinkIndex(os.Getenv("LINK_INDEX"), tiers)
+    if err != nil {
+        logger.Println("Error initializing link index.", err.Error())
+        panic(err.Error())
+    }
+
+    skipMaxKeys, err := envInt64("SKIP_INDEX_MAX_KEYS", DEFAULT_SKIP_INDEX_MAX_KEYS)
+    if err == nil && skipMaxKeys < 1 {
+        err = fmt.Errorf("SKIP_INDEX_MAX_KEYS must be at least 1, got %d", skipMaxKeys)
+    }
+    if err != nil {
+        logger.Println("Error initializing skip index.", err.Error())
+        panic(err.Error())
+    }
+
+    skipMaxWalk, err := envInt64("SKIP_INDEX_MAX_WALK", DEFAULT_SKIP_INDEX_MAX_WALK)
+    if err != nil {
+        logger.Println("Error initializing skip index.", err.Error())
+        panic(err.Error())
+    }
+
+    skips = chain.NewSkipIndex(int(skipMaxKeys), int(skipMaxWalk), previousEvents)
+    segmentMaxIds, err := envInt64("SEGMENT_CACHE_MAX_IDS", DEFAULT_SEGMENT_CACHE_MAX_IDS)
+    if err != nil {
+        logger.Println("Error initializing segment cache.", err.Error())
+        panic(err.Error())
+    }
+    segments = chain.NewSegments(int(segmentMaxIds))
+    logIndex = chain.NewLogIndex(LOG_INDEX_MAX_KEYS)
 }
 
 func main() {
     r := buildRoutes()
 
     n := negroni.New()
+    n.Use(negroni.HandlerFunc(timeoutMiddleware))
     n.UseHandler(r)
 
     port := os.Getenv("PORT")
@@ -83,18 +177,62 @@ func main() {
         port = "3000"
     }
 
+    p, err := newPrefetcher()
+    if err != nil {
+        logger.Println("Error initializing prefetcher.", err.Error())
+        panic(err.Error())
+    }
+    if p != nil {
+        go p.run()
+    }
+
+    verifyJob, err = newVerifier()
+    if err != nil {
+        logger.Println("Error initializing verification job.", err.Error())
+        panic(err.Error())
+    }
+    if verifyJob != nil {
+        go verifyJob.run()
+    }
+
+    adminPort := os.Getenv("ADMIN_PORT")
+    if adminPort != "" {
+        go runAdmin(":" + adminPort)
+    }
+
     n.Run(":" + port)
 }
 
 func buildRoutes() http.Handler {
     r := mux.NewRouter()
     r.HandleFunc("/", statusHandler).Methods("GET")
+    r.HandleFunc("/logs", listLogsHandler).Methods("GET")
+    r.HandleFunc("/logs:batchGet", batchGetLogsHandler).Methods("POST")
     r.HandleFunc("/logs/{logId}", readLogHandler).Methods("GET")
     r.HandleFunc("/logs/{logId}/events", readEventsHandler).Methods("GET")
+    r.HandleFunc("/logs/{logId}/events/{eventId}/position", eventPositionHandler).Methods("GET")
+    r.HandleFunc("/logs/{logId}/verify", verifyLogHandler).Methods("GET")
+    r.HandleFunc("/logs/{logId}/proof", proofHandler).Methods("GET")
+    r.HandleFunc("/logs/{logId}/diff", diffHandler).Methods("GET")
+    r.HandleFunc("/events/{eventId}/logs", eventLogsHandler).Methods("GET")
+    r.HandleFunc("/.well-known/event-log-reader/signing-key", signingKeyHandler).Methods("GET")
 
     return r
 }
 
+// timeoutMiddleware bounds the time spent on each request. Work on the request's
+// context also stops when the client disconnects.
+func timeoutMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+    if requestTimeout <= 0 {
+        next(w, r)
+        return
+    }
+
+    ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+    defer cancel()
+    next(w, r.WithContext(ctx))
+}
+
 func statusHandler(w http.ResponseWriter, r *http.Request) {
     fmt.Fprint(w, "The service is online!\n")
 }
@@ -114,20 +252,22 @@ func readLogHandler(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    headEventId, err := getLogHead(db, logId)
-    if err == sql.ErrNoRows {
-        http.Error(w, err.Error(), http.StatusNotFound)
-        return
-    } else if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+    headEventId, err := getLogHead(r.Context(), logId)
+    if err != nil {
+        writeError(w, err)
         return
     }
 
+    data := &readLogResponse{
+        LogID: logId.String(),
+        Head: headEventId.String(),
+    }
+    if signer != nil {
+        signer.sign(data, logId, headEventId)
+    }
+
     resp := &jsonResponse{
-        Data: &readLogResponse{
-            LogID: logId.String(),
-            Head: headEventId.String(),
-        },
+        Data: data,
     }
     encoder := json.NewEncoder(w)
     err = encoder.Encode(resp)
@@ -162,37 +302,24 @@ func readEventsHandler(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    headEventId, err := getLogHead(db, logId)
-    if err == sql.ErrNoRows {
-        http.Error(w, err.Error(), http.StatusNotFound)
-        return
-    } else if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
-        return
-    }
-
-    events, err := getEventHistory(headEventId, after)
+    headEventId, err := getLogHead(r.Context(), logId)
     if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        writeError(w, err)
         return
     }
 
-    l := len(events)
-    jsonEvents := make([]*eventJson, l)
-    for i := 0; i < l; i++ {
-        e := events[l - 1 - i]
-        id := e.ID()
-
-        jsonEvents[i] = &eventJson{
-            EventID: id.String(),
-            Type: e.Type,
-            Data: base64.StdEncoding.EncodeToString(e.Data),
-        }
+    events, err := getLogHistory(r.Context(), logId, headEventId, after)
+    if err == errAfterNotInHistory {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    } else if err != nil {
+        writeError(w, err)
+        return
     }
 
     resp := &jsonResponse{
         Data: &readEventsResponse{
-            Events: jsonEvents,
+            Events: eventsJson(events),
         },
     }
 
@@ -204,112 +331,123 @@ func readEventsHandler(w http.ResponseWriter, r *http.Request) {
     }
 }
 
-type jsonResponse struct {
-    Data interface{} `json:"data,omitempty"`
-    Error string `json:"error,omitempty"`
-}
-
-type readLogResponse struct {
-    LogID string `json:"logId"`
-    Head string `json:"head"`
-}
-
-type readEventsResponse struct {
-    Events []*eventJson `json:"events"`
-}
-
-type eventJson struct {
-    EventID string `json:"eventId"`
-    Type string `json:"type"`
-    Data string `json:"data"`
-}
-
-func getLogHead(conn *sql.DB, id eventLog.LogID) (event.EventID, error) {
-    var head []byte
-    err := conn.QueryRow(`SELECT head FROM logs WHERE ext_lookup_key=$1`, id[:]).Scan(&head)
-    if err == sql.ErrNoRows {
-        // Return the Zero Event if there is no record of the log (we treat an unknown log as an empty log)
-        return event.EventID{}, nil
+func eventPositionHandler(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    logId := eventLog.LogID{}
+    err := logId.Parse(vars["logId"])
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
     }
 
+    eventId := event.EventID{}
+    err = eventId.Parse(vars["eventId"])
     if err != nil {
-        logger.Println("Error executing SELECT for log head lookup.", err.Error())
-        return event.EventID{}, err
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
     }
 
-    var out event.EventID
-    copy(out[:], head)
-    return out, nil
-}
-
-func getEventHistory(head event.EventID, last event.EventID) ([]*event.Event, error) {
-    zero := event.EventID{}
-
-    out := make([]*event.Event, 0)
-    for head != last && head != zero {
-        e, err := getEvent(head)
-        if err != nil {
-            return []*event.Event{}, err
-        }
-
-        out = append(out, e)
-        head = e.PreviousEvent
+    ctx := r.Context()
+    headEventId, err := getLogHead(ctx, logId)
+    if err != nil {
+        writeError(w, err)
+        return
     }
 
-    return out, nil
-}
-
-func getEvent(id event.EventID) (*event.Event, error) {
-    if e, ok := eventCache.Get(id); ok {
-        return e, nil
+    length, err := skips.Height(ctx, headEventId)
+    if err != nil {
+        writeError(w, err)
+        return
     }
 
-    if e, ok := redisGet(id); ok {
-        return e, nil
+    ok, err := skips.IsAncestor(ctx, eventId, headEventId)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    if !ok || eventId == (event.EventID{}) {
+        http.Error(w, "The event is not in the log's history.", http.StatusNotFound)
+        return
     }
 
-    e, err := eventReader.GetEvent(id)
+    position, err := skips.Height(ctx, eventId)
     if err != nil {
-        return nil, err
+        writeError(w, err)
+        return
     }
 
-    go addToCaches(e)
-
-    return e, nil
+    writeJson(w, &eventPositionResponse{
+        LogID: logId.String(),
+        EventID: eventId.String(),
+        Position: position,
+        Length: length,
+    })
 }
 
-func addToCaches(e *event.Event) {
-    eventCache.Add(e)
+// writeError responds to a failed read. Upstream failures are reported as gateway
+// errors and running out of time as a gateway timeout. If the client has gone away
+// there is no one to respond to.
+func writeError(w http.ResponseWriter, err error) {
+    switch err {
+    case context.Canceled:
+        return
+    case context.DeadlineExceeded:
+        http.Error(w, err.Error(), http.StatusGatewayTimeout)
+        return
+    case source.ErrUnavailable, chain.ErrWalkTooLong:
+        http.Error(w, err.Error(), http.StatusServiceUnavailable)
+        return
+    case source.ErrNotFound:
+        // The log refers to an event the upstream doesn't have.
+        http.Error(w, err.Error(), http.StatusBadGateway)
+        return
+    }
 
-    redisSet(e)
+    switch err.(type) {
+    case *source.StatusError, *source.ResponseError, *cache.MismatchError, net.Error:
+        http.Error(w, err.Error(), http.StatusBadGateway)
+        return
+    }
+
+    http.Error(w, err.Error(), http.StatusInternalServerError)
 }
 
-func redisSet(e *event.Event) {
-    id := e.ID()
+type jsonResponse struct {
+    Data interface{} `json:"data,omitempty"`
+    Error string `json:"error,omitempty"`
+}
 
-    redisClient.Set(id.String(), e.String(), 0)
+type readLogResponse struct {
+    LogID string `json:"logId"`
+    Head string `json:"head"`
+    // Set when responses are signed.
+    Timestamp string `json:"timestamp,omitempty"`
+    KeyID string `json:"keyId,omitempty"`
+    Signature string `json:"signature,omitempty"`
 }
 
-func redisGet(id event.EventID) (*event.Event, bool) {
-    res, err := redisClient.Get(id.String()).Result()
-    if err != nil {
-        if err != redis.Nil {
-            logger.Println("Redis error:", err.Error())
-        }
-        return nil, false
-    }
+type readEventsResponse struct {
+    Events []*eventJson `json:"events"`
+}
 
-    var e event.Event
-    err = e.Parse(res)
-    if err != nil {
-        logger.Println("Error deserializing redis result.", err.Error())
-        return nil, false
-    }
-    return &e, true
+type eventPositionResponse struct {
+    LogID string `json:"logId"`
+    EventID string `json:"eventId"`
+    Position uint64 `json:"position"`
+    Length uint64 `json:"length"`
 }
 
-type redisEventSerializer struct {
-    PrevID string `json:"previousId"`
+type eventJson struct {
+    EventID string `json:"eventId"`
     Type string `json:"type"`
     Data string `json:"data"`
 }
+
+func getLogHead(ctx context.Context, id eventLog.LogID) (event.EventID, error) {
+    head, err := heads.Head(ctx, id)
+    if err != nil {
+        logger.Println("Error looking up log head.", err.Error())
+        return event.EventID{}, err
+    }
+    return head, nil
+}
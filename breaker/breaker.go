@@ -0,0 +1,113 @@
+package breaker
+
+import (
+    "sync"
+    "time"
+)
+
+type State int
+
+const (
+    Closed State = iota
+    Open
+    HalfOpen
+)
+
+func (s State) String() string {
+    switch s {
+    case Closed:
+        return "closed"
+    case Open:
+        return "open"
+    case HalfOpen:
+        return "half-open"
+    }
+    return "unknown"
+}
+
+// Breaker is a circuit breaker. It opens after Threshold consecutive failures and
+// then rejects calls. If Cooldown is set, a single trial call is let through once it
+// has elapsed and its outcome closes or reopens the breaker. Otherwise the breaker
+// stays open until Reset, typically called by a health probe.
+type Breaker struct {
+    threshold int
+    cooldown time.Duration
+
+    mu sync.Mutex
+    state State
+    failures int
+    openedAt time.Time
+}
+
+func New(threshold int, cooldown time.Duration) *Breaker {
+    if threshold < 1 {
+        threshold = 1
+    }
+
+    return &Breaker{
+        threshold: threshold,
+        cooldown: cooldown,
+    }
+}
+
+// Allow reports whether a call may proceed.
+func (b *Breaker) Allow() bool {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    switch b.state {
+    case Closed:
+        return true
+    case Open, HalfOpen:
+        // A trial whose outcome was never reported doesn't block the next one.
+        if b.cooldown > 0 && time.Since(b.openedAt) >= b.cooldown {
+            b.state = HalfOpen
+            b.openedAt = time.Now()
+            return true
+        }
+    }
+    return false
+}
+
+func (b *Breaker) Success() {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    b.failures = 0
+    b.state = Closed
+}
+
+func (b *Breaker) Failure() {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    b.failures++
+    if b.state == HalfOpen || b.failures >= b.threshold {
+        b.trip()
+    }
+}
+
+// Trip opens the breaker immediately.
+func (b *Breaker) Trip() {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    b.trip()
+}
+
+// Reset closes the breaker.
+func (b *Breaker) Reset() {
+    b.Success()
+}
+
+func (b *Breaker) State() State {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    return b.state
+}
+
+func (b *Breaker) trip() {
+    b.state = Open
+    b.openedAt = time.Now()
+}
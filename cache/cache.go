@@ -1,11 +1,18 @@
 package cache
 
-import "github.com/tobyjsullivan/ues-sdk/event"
+import (
+    "context"
+    "sync"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
 
 type EventCache struct {
+    mu sync.Mutex
     data map[event.EventID]*event.Event
     list *list
     maxSize int
+    counters Counters
 }
 
 func New(maxSize int) *EventCache {
@@ -16,16 +23,25 @@ func New(maxSize int) *EventCache {
     }
 }
 
-func (c *EventCache) Get(id event.EventID) (*event.Event, bool) {
+func (c *EventCache) Get(ctx context.Context, id event.EventID) (*event.Event, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
     e, ok := c.data[id]
     if ok {
+        c.counters.Hit()
         c.list.remove(id)
         c.list.prepend(id)
+    } else {
+        c.counters.Miss()
     }
     return e, ok
 }
 
 func (c *EventCache) Add(e *event.Event) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
     id := e.ID()
     if _, ok := c.data[id]; ok {
         return
@@ -38,6 +54,52 @@ func (c *EventCache) Add(e *event.Event) {
     }
 }
 
+func (c *EventCache) Name() string {
+    return "memory"
+}
+
+// Remove evicts a single event from the cache, reporting whether it was present.
+func (c *EventCache) Remove(id event.EventID) (bool, error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if _, ok := c.data[id]; !ok {
+        return false, nil
+    }
+
+    c.list.remove(id)
+    delete(c.data, id)
+    c.counters.Evict()
+    return true, nil
+}
+
+// Flush evicts every event in the cache and returns how many were removed.
+func (c *EventCache) Flush() int {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    n := len(c.data)
+    c.data = make(map[event.EventID]*event.Event)
+    c.list = &list{}
+    c.counters.EvictN(n)
+    return n
+}
+
+func (c *EventCache) Len() int {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    return len(c.data)
+}
+
+func (c *EventCache) MaxSize() int {
+    return c.maxSize
+}
+
+func (c *EventCache) Stats() Stats {
+    return c.counters.Snapshot()
+}
+
 func (c *EventCache) removeOldest() {
     oldest, any := c.list.last()
     if !any {
@@ -46,4 +108,5 @@ func (c *EventCache) removeOldest() {
 
     c.list.remove(oldest)
     delete(c.data, oldest)
+    c.counters.Evict()
 }
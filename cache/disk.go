@@ -0,0 +1,351 @@
+package cache
+
+import (
+    linked "container/list"
+    "context"
+    "encoding/binary"
+    "encoding/hex"
+    "errors"
+    "hash/crc32"
+    "io/ioutil"
+    "log"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+const (
+    diskMagic = "ELRC"
+    diskHeaderLen = 8 // magic + crc32
+    diskTempPrefix = ".tmp-"
+)
+
+var errCorruptEntry = errors.New("corrupt disk cache entry")
+
+// DiskTier keeps events as one file per event under a directory so that the cache
+// survives restarts. Files are evicted least-recently-used once the total size
+// exceeds the cap. Every file carries a CRC32 checksum of its contents.
+type DiskTier struct {
+    dir string
+    maxBytes int64
+    logger *log.Logger
+
+    mu sync.Mutex
+    lru *linked.List
+    entries map[event.EventID]*linked.Element
+    size int64
+
+    counters Counters
+}
+
+type diskEntry struct {
+    id event.EventID
+    size int64
+}
+
+// NewDiskTier opens the cache directory, creating it if necessary, and indexes any
+// entries left by a previous process.
+func NewDiskTier(dir string, maxBytes int64, logger *log.Logger) (*DiskTier, error) {
+    err := os.MkdirAll(dir, 0755)
+    if err != nil {
+        return nil, err
+    }
+
+    t := &DiskTier{
+        dir: dir,
+        maxBytes: maxBytes,
+        logger: logger,
+        lru: linked.New(),
+        entries: make(map[event.EventID]*linked.Element),
+    }
+
+    err = t.load()
+    if err != nil {
+        return nil, err
+    }
+
+    return t, nil
+}
+
+func (t *DiskTier) Name() string {
+    return "disk"
+}
+
+func (t *DiskTier) Get(ctx context.Context, id event.EventID) (*event.Event, bool) {
+    if ctx.Err() != nil {
+        return nil, false
+    }
+
+    t.mu.Lock()
+    el, ok := t.entries[id]
+    if ok {
+        t.lru.MoveToFront(el)
+    }
+    t.mu.Unlock()
+
+    if !ok {
+        t.counters.Miss()
+        return nil, false
+    }
+
+    path := t.path(id)
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+        t.logger.Println("Error reading disk cache entry.", err.Error())
+        t.drop(id)
+        t.counters.Miss()
+        return nil, false
+    }
+
+    e, err := decodeDiskEntry(b)
+    if err == nil && e.ID() != id {
+        err = errCorruptEntry
+    }
+    if err != nil {
+        t.logger.Println("Discarding disk cache entry.", id.String(), err.Error())
+        t.drop(id)
+        t.counters.Miss()
+        return nil, false
+    }
+
+    // The modification time records recency so the LRU order survives a restart.
+    now := time.Now()
+    os.Chtimes(path, now, now)
+
+    t.counters.Hit()
+    return e, true
+}
+
+func (t *DiskTier) Add(e *event.Event) {
+    id := e.ID()
+
+    t.mu.Lock()
+    _, ok := t.entries[id]
+    t.mu.Unlock()
+    if ok {
+        return
+    }
+
+    b := encodeDiskEntry(e)
+    path := t.path(id)
+    err := writeFileAtomic(path, b)
+    if err != nil {
+        t.logger.Println("Error writing disk cache entry.", err.Error())
+        return
+    }
+
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    if _, ok := t.entries[id]; ok {
+        return
+    }
+    t.entries[id] = t.lru.PushFront(&diskEntry{id: id, size: int64(len(b))})
+    t.size += int64(len(b))
+    t.evict()
+}
+
+func (t *DiskTier) Remove(id event.EventID) (bool, error) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    el, ok := t.entries[id]
+    if !ok {
+        return false, nil
+    }
+
+    err := t.removeEntry(el)
+    if err != nil {
+        return false, err
+    }
+    t.counters.Evict()
+    return true, nil
+}
+
+func (t *DiskTier) Len() int {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    return len(t.entries)
+}
+
+// Bytes returns the total size of the cache files.
+func (t *DiskTier) Bytes() int64 {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    return t.size
+}
+
+func (t *DiskTier) MaxBytes() int64 {
+    return t.maxBytes
+}
+
+func (t *DiskTier) Stats() Stats {
+    return t.counters.Snapshot()
+}
+
+// evict removes the least recently used entries until the cache is within its cap.
+// Callers must hold t.mu.
+func (t *DiskTier) evict() {
+    for t.size > t.maxBytes && t.lru.Len() > 0 {
+        err := t.removeEntry(t.lru.Back())
+        if err != nil {
+            t.logger.Println("Error evicting disk cache entry.", err.Error())
+            return
+        }
+        t.counters.Evict()
+    }
+}
+
+// drop forgets a damaged entry and deletes its file.
+func (t *DiskTier) drop(id event.EventID) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    if el, ok := t.entries[id]; ok {
+        t.removeEntry(el)
+    }
+}
+
+// Callers must hold t.mu.
+func (t *DiskTier) removeEntry(el *linked.Element) error {
+    entry := el.Value.(*diskEntry)
+    err := os.Remove(t.path(entry.id))
+    if err != nil && !os.IsNotExist(err) {
+        return err
+    }
+
+    t.lru.Remove(el)
+    delete(t.entries, entry.id)
+    t.size -= entry.size
+    return nil
+}
+
+func (t *DiskTier) path(id event.EventID) string {
+    name := id.String()
+    return filepath.Join(t.dir, name[:2], name)
+}
+
+// load indexes existing entries, oldest access first, and trims the cache to its cap.
+func (t *DiskTier) load() error {
+    type found struct {
+        id event.EventID
+        size int64
+        modified time.Time
+    }
+
+    entries := make([]found, 0)
+    // Entries live in shard directories named by the first byte of their ID. Anything
+    // else under the cache directory isn't ours and is left alone.
+    err := filepath.Walk(t.dir, func(path string, info os.FileInfo, err error) error {
+        if err != nil {
+            return err
+        }
+        if path == t.dir {
+            return nil
+        }
+        if info.IsDir() {
+            if filepath.Dir(path) != t.dir || !isDiskShard(info.Name()) {
+                return filepath.SkipDir
+            }
+            return nil
+        }
+        if filepath.Dir(path) == t.dir {
+            return nil
+        }
+
+        name := info.Name()
+        if strings.HasPrefix(name, diskTempPrefix) {
+            // Leftover temp file from an interrupted write.
+            os.Remove(path)
+            return nil
+        }
+
+        var id event.EventID
+        if len(name) != len(id) * 2 || id.Parse(name) != nil || name[:2] != filepath.Base(filepath.Dir(path)) {
+            return nil
+        }
+
+        entries = append(entries, found{id: id, size: info.Size(), modified: info.ModTime()})
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+
+    sort.Slice(entries, func(i, j int) bool {
+        return entries[i].modified.Before(entries[j].modified)
+    })
+
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    for _, f := range entries {
+        t.entries[f.id] = t.lru.PushFront(&diskEntry{id: f.id, size: f.size})
+        t.size += f.size
+    }
+    t.evict()
+
+    return nil
+}
+
+func isDiskShard(name string) bool {
+    if len(name) != 2 {
+        return false
+    }
+    _, err := hex.DecodeString(name)
+    return err == nil && strings.ToLower(name) == name
+}
+
+// A disk entry is the magic bytes, a CRC32 of the body, then the body: the event in
+// the versioned encoding used by MarshalEvent.
+func encodeDiskEntry(e *event.Event) []byte {
+    body := MarshalEvent(e)
+
+    out := make([]byte, diskHeaderLen, diskHeaderLen + len(body))
+    copy(out, diskMagic)
+    binary.BigEndian.PutUint32(out[len(diskMagic):], crc32.ChecksumIEEE(body))
+    return append(out, body...)
+}
+
+func decodeDiskEntry(b []byte) (*event.Event, error) {
+    if len(b) < diskHeaderLen || string(b[:len(diskMagic)]) != diskMagic {
+        return nil, errCorruptEntry
+    }
+    body := b[diskHeaderLen:]
+    if binary.BigEndian.Uint32(b[len(diskMagic):]) != crc32.ChecksumIEEE(body) {
+        return nil, errCorruptEntry
+    }
+
+    return UnmarshalEvent(body)
+}
+
+func writeFileAtomic(path string, b []byte) error {
+    dir := filepath.Dir(path)
+    err := os.MkdirAll(dir, 0755)
+    if err != nil {
+        return err
+    }
+
+    f, err := ioutil.TempFile(dir, diskTempPrefix)
+    if err != nil {
+        return err
+    }
+    _, err = f.Write(b)
+    if err == nil {
+        err = f.Sync()
+    }
+    if cerr := f.Close(); err == nil {
+        err = cerr
+    }
+    if err != nil {
+        os.Remove(f.Name())
+        return err
+    }
+
+    return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,167 @@
+package cache
+
+import (
+    "context"
+    "io/ioutil"
+    "log"
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+var discardLogger = log.New(ioutil.Discard, "", 0)
+
+// tempDir creates a directory for a test's cache. Call the returned func to remove
+// it.
+func tempDir(t *testing.T) (string, func()) {
+    dir, err := ioutil.TempDir("", "disk-tier-test")
+    if err != nil {
+        t.Fatal(err)
+    }
+    return dir, func() { os.RemoveAll(dir) }
+}
+
+func openDiskTier(t *testing.T, dir string, maxBytes int64) *DiskTier {
+    tier, err := NewDiskTier(dir, maxBytes, discardLogger)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return tier
+}
+
+func exists(path string) bool {
+    _, err := os.Stat(path)
+    return err == nil
+}
+
+func TestDiskTierDropsCorruptEntries(t *testing.T) {
+    dir, cleanup := tempDir(t)
+    defer cleanup()
+    tier := openDiskTier(t, dir, 1 << 20)
+
+    e := testEvent("will be corrupted")
+    tier.Add(e)
+    path := tier.path(e.ID())
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    b[len(b) - 1] ^= 0xff
+    if err := ioutil.WriteFile(path, b, 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    if _, ok := tier.Get(context.Background(), e.ID()); ok {
+        t.Errorf("got a hit for a corrupt entry")
+    }
+    if tier.Len() != 0 || tier.Bytes() != 0 {
+        t.Errorf("got %d entries of %d bytes, want the entry forgotten", tier.Len(), tier.Bytes())
+    }
+    if exists(path) {
+        t.Errorf("corrupt entry's file was not deleted")
+    }
+}
+
+func TestDiskTierEvictsLeastRecentlyUsed(t *testing.T) {
+    dir, cleanup := tempDir(t)
+    defer cleanup()
+
+    a, b, c := testEvent("event a"), testEvent("event b"), testEvent("event c")
+    size := int64(len(encodeDiskEntry(a)))
+    tier := openDiskTier(t, dir, 2 * size)
+    ctx := context.Background()
+
+    tier.Add(a)
+    tier.Add(b)
+    // Reading a makes b the least recently used.
+    if _, ok := tier.Get(ctx, a.ID()); !ok {
+        t.Fatal("missed an entry within the cap")
+    }
+    tier.Add(c)
+
+    if tier.Len() != 2 || tier.Bytes() != 2 * size {
+        t.Errorf("got %d entries of %d bytes, want 2 of %d", tier.Len(), tier.Bytes(), 2 * size)
+    }
+    for _, want := range []struct {
+        name string
+        e *event.Event
+        held bool
+    }{
+        {"a", a, true},
+        {"b", b, false},
+        {"c", c, true},
+    } {
+        if _, ok := tier.Get(ctx, want.e.ID()); ok != want.held {
+            t.Errorf("%s: got held %t, want %t", want.name, ok, want.held)
+        }
+    }
+    if exists(tier.path(b.ID())) {
+        t.Errorf("evicted entry's file was not deleted")
+    }
+}
+
+func TestDiskTierReopens(t *testing.T) {
+    dir, cleanup := tempDir(t)
+    defer cleanup()
+
+    a, b := testEvent("event a"), testEvent("event b")
+    tier := openDiskTier(t, dir, 1 << 20)
+    tier.Add(a)
+    tier.Add(b)
+
+    // Files the tier didn't write, and a temp file left by an interrupted write.
+    shard := filepath.Dir(tier.path(a.ID()))
+    foreign := []string{
+        filepath.Join(dir, "notes.txt"),
+        filepath.Join(dir, ".tmp-top-level"),
+        filepath.Join(dir, "other", ".tmp-not-a-shard"),
+        filepath.Join(dir, "zz", "not-hex"),
+        filepath.Join(shard, "not-an-id"),
+    }
+    leftover := filepath.Join(shard, diskTempPrefix + "interrupted")
+    for _, path := range append(foreign, leftover) {
+        os.MkdirAll(filepath.Dir(path), 0755)
+        if err := ioutil.WriteFile(path, []byte("not an entry"), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    reopened := openDiskTier(t, dir, 1 << 20)
+    if reopened.Len() != 2 || reopened.Bytes() != tier.Bytes() {
+        t.Errorf("got %d entries of %d bytes, want 2 of %d", reopened.Len(), reopened.Bytes(), tier.Bytes())
+    }
+    for _, e := range []*event.Event{a, b} {
+        got, ok := reopened.Get(context.Background(), e.ID())
+        if !ok || got.ID() != e.ID() {
+            t.Errorf("missed %s after reopening", string(e.Data))
+        }
+    }
+
+    for _, path := range foreign {
+        if !exists(path) {
+            t.Errorf("%s was deleted", path)
+        }
+    }
+    if exists(leftover) {
+        t.Errorf("leftover temp file was not deleted")
+    }
+}
+
+func TestDiskTierReopensWithinCap(t *testing.T) {
+    dir, cleanup := tempDir(t)
+    defer cleanup()
+
+    a, b := testEvent("event a"), testEvent("event b")
+    size := int64(len(encodeDiskEntry(a)))
+    tier := openDiskTier(t, dir, 2 * size)
+    tier.Add(a)
+    tier.Add(b)
+
+    // A smaller cap on restart trims the cache to fit.
+    reopened := openDiskTier(t, dir, size)
+    if reopened.Len() != 1 || reopened.Bytes() != size {
+        t.Errorf("got %d entries of %d bytes, want 1 of %d", reopened.Len(), reopened.Bytes(), size)
+    }
+}
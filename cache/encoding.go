@@ -0,0 +1,77 @@
+package cache
+
+import (
+    "encoding/binary"
+    "errors"
+    "fmt"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// The first byte of an encoded event is its format version. Older formats must stay
+// readable so that the encoding can change without flushing shared caches.
+const (
+    // encodingV1 is the version byte followed by the previous event ID, the uvarint
+    // length of the type, the type and the data.
+    encodingV1 byte = 1
+
+    // legacyJsonPrefix starts the JSON format produced by event.Event.String(),
+    // which was stored before versioned encodings existed.
+    legacyJsonPrefix byte = '{'
+)
+
+var errShortEncoding = errors.New("encoded event is truncated")
+
+// MarshalEvent encodes an event in the current binary format.
+func MarshalEvent(e *event.Event) []byte {
+    lenBuf := make([]byte, binary.MaxVarintLen64)
+    n := binary.PutUvarint(lenBuf, uint64(len(e.Type)))
+
+    out := make([]byte, 0, 1 + len(e.PreviousEvent) + n + len(e.Type) + len(e.Data))
+    out = append(out, encodingV1)
+    out = append(out, e.PreviousEvent[:]...)
+    out = append(out, lenBuf[:n]...)
+    out = append(out, e.Type...)
+    out = append(out, e.Data...)
+    return out
+}
+
+// UnmarshalEvent decodes an event in any supported format.
+func UnmarshalEvent(b []byte) (*event.Event, error) {
+    if len(b) == 0 {
+        return nil, errShortEncoding
+    }
+
+    switch b[0] {
+    case encodingV1:
+        return unmarshalV1(b[1:])
+    case legacyJsonPrefix:
+        var e event.Event
+        err := e.Parse(string(b))
+        if err != nil {
+            return nil, err
+        }
+        return &e, nil
+    }
+
+    return nil, fmt.Errorf("unknown event encoding version %d", b[0])
+}
+
+func unmarshalV1(b []byte) (*event.Event, error) {
+    var e event.Event
+    if len(b) < len(e.PreviousEvent) {
+        return nil, errShortEncoding
+    }
+    copy(e.PreviousEvent[:], b)
+    b = b[len(e.PreviousEvent):]
+
+    typeLen, n := binary.Uvarint(b)
+    if n <= 0 || uint64(len(b) - n) < typeLen {
+        return nil, errShortEncoding
+    }
+    b = b[n:]
+    e.Type = string(b[:typeLen])
+    e.Data = event.EventData(append([]byte{}, b[typeLen:]...))
+
+    return &e, nil
+}
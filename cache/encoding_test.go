@@ -0,0 +1,79 @@
+package cache
+
+import (
+    "bytes"
+    "testing"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+func sameEvent(a, b *event.Event) bool {
+    return a.PreviousEvent == b.PreviousEvent && a.Type == b.Type && bytes.Equal(a.Data, b.Data)
+}
+
+func TestEventEncodingRoundTrip(t *testing.T) {
+    prev := testEvent("previous").ID()
+    cases := []*event.Event{
+        testEvent("some data"),
+        {PreviousEvent: prev, Type: "Chained", Data: event.EventData{0, 1, 2, 255}},
+        {PreviousEvent: prev, Type: "", Data: event.EventData{}},
+        {Type: string(make([]byte, 300)), Data: event.EventData("long type")},
+    }
+
+    for i, e := range cases {
+        b := MarshalEvent(e)
+        if b[0] != encodingV1 {
+            t.Errorf("case %d: got version %d", i, b[0])
+        }
+        got, err := UnmarshalEvent(b)
+        if err != nil {
+            t.Fatalf("case %d: %s", i, err.Error())
+        }
+        if !sameEvent(got, e) || got.ID() != e.ID() {
+            t.Errorf("case %d: got %s, want %s", i, got.String(), e.String())
+        }
+    }
+}
+
+func TestUnmarshalLegacyJson(t *testing.T) {
+    e := &event.Event{
+        PreviousEvent: testEvent("previous").ID(),
+        Type: "Legacy",
+        Data: event.EventData("stored before versioning"),
+    }
+
+    // What the cache tiers held before versioned encodings existed.
+    got, err := UnmarshalEvent([]byte(e.String()))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !sameEvent(got, e) {
+        t.Errorf("got %s, want %s", got.String(), e.String())
+    }
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+    full := MarshalEvent(&event.Event{
+        PreviousEvent: testEvent("previous").ID(),
+        Type: "Truncated",
+        Data: event.EventData("data"),
+    })
+
+    cases := []struct {
+        name string
+        b []byte
+    }{
+        {"empty", []byte{}},
+        {"unknown version", append([]byte{2}, full[1:]...)},
+        {"version only", full[:1]},
+        {"short previous ID", full[:20]},
+        {"no type length", full[:33]},
+        {"short type", full[:38]},
+        {"bad legacy JSON", []byte(`{"previous"`)},
+    }
+    for _, tc := range cases {
+        if _, err := UnmarshalEvent(tc.b); err == nil {
+            t.Errorf("%s: got no error", tc.name)
+        }
+    }
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+    "context"
+    "sync"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// flightGroup collapses concurrent loads of the same event into a single call.
+type flightGroup struct {
+    mu sync.Mutex
+    calls map[event.EventID]*flight
+}
+
+type flight struct {
+    done chan struct{}
+    e *event.Event
+    err error
+}
+
+// do runs fn unless a call for the same id is already in flight, in which case it
+// waits for that call and returns its result. shared reports whether the result came
+// from another caller's call. fn runs in its own goroutine so that any caller can
+// stop waiting when its context is done without cancelling the call for the others.
+func (g *flightGroup) do(ctx context.Context, id event.EventID, fn func() (*event.Event, error)) (e *event.Event, err error, shared bool) {
+    g.mu.Lock()
+    if g.calls == nil {
+        g.calls = make(map[event.EventID]*flight)
+    }
+    f, shared := g.calls[id]
+    if !shared {
+        f = &flight{done: make(chan struct{})}
+        g.calls[id] = f
+        go g.call(id, f, fn)
+    }
+    g.mu.Unlock()
+
+    select {
+    case <-f.done:
+        return f.e, f.err, shared
+    case <-ctx.Done():
+        return nil, ctx.Err(), shared
+    }
+}
+
+func (g *flightGroup) call(id event.EventID, f *flight, fn func() (*event.Event, error)) {
+    f.e, f.err = fn()
+
+    g.mu.Lock()
+    delete(g.calls, id)
+    g.mu.Unlock()
+
+    close(f.done)
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+    "context"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// blockingSource answers with its event once release is closed, counting calls.
+type blockingSource struct {
+    e *event.Event
+    release chan struct{}
+    calls int32
+}
+
+func (s *blockingSource) GetEvent(ctx context.Context, id event.EventID) (*event.Event, error) {
+    atomic.AddInt32(&s.calls, 1)
+    <-s.release
+    return s.e, nil
+}
+
+// missTier never holds anything. It counts writes, and marks arrivals so a test can
+// tell when every reader has missed it and gone on to the source.
+type missTier struct {
+    arrivals sync.WaitGroup
+    adds int32
+}
+
+func (t *missTier) Name() string {
+    return "miss"
+}
+
+func (t *missTier) Get(ctx context.Context, id event.EventID) (*event.Event, bool) {
+    t.arrivals.Done()
+    return nil, false
+}
+
+func (t *missTier) Add(e *event.Event) {
+    atomic.AddInt32(&t.adds, 1)
+}
+
+func (t *missTier) Remove(id event.EventID) (bool, error) {
+    return false, nil
+}
+
+func (t *missTier) Stats() Stats {
+    return Stats{}
+}
+
+// waitFor polls until cond holds or a second has passed.
+func waitFor(cond func() bool) bool {
+    for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+        if cond() {
+            return true
+        }
+    }
+    return cond()
+}
+
+func TestConcurrentReadsShareOneFetch(t *testing.T) {
+    const readers = 10
+    e := testEvent("shared")
+    src := &blockingSource{e: e, release: make(chan struct{})}
+    tier := &missTier{}
+    tier.arrivals.Add(readers)
+    s := NewStore(src, &StoreOptions{WriteQueueSize: readers}, tier)
+
+    var wg sync.WaitGroup
+    errs := make(chan error, readers)
+    for i := 0; i < readers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            got, err := s.GetEvent(context.Background(), e.ID())
+            if err == nil && got != e {
+                t.Errorf("got a different event")
+            }
+            errs <- err
+        }()
+    }
+
+    // Give the readers that missed the tier time to join the fetch before it ends.
+    tier.arrivals.Wait()
+    time.Sleep(20 * time.Millisecond)
+    close(src.release)
+    wg.Wait()
+    close(errs)
+
+    for err := range errs {
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+    if calls := atomic.LoadInt32(&src.calls); calls != 1 {
+        t.Errorf("got %d source calls, want 1", calls)
+    }
+    stats := s.SourceStats()
+    if stats.Fetches != 1 || stats.Shared != readers - 1 {
+        t.Errorf("got %d fetches and %d shared reads, want 1 and %d", stats.Fetches, stats.Shared, readers - 1)
+    }
+
+    if !waitFor(func() bool { return atomic.LoadInt32(&tier.adds) > 0 }) {
+        t.Fatal("the fetched event was never written to the tier")
+    }
+    time.Sleep(20 * time.Millisecond)
+    if adds := atomic.LoadInt32(&tier.adds); adds != 1 {
+        t.Errorf("got %d tier writes, want 1", adds)
+    }
+}
+
+func TestCancelledReadDoesNotFailOthers(t *testing.T) {
+    e := testEvent("shared")
+    src := &blockingSource{e: e, release: make(chan struct{})}
+    tier := &missTier{}
+    tier.arrivals.Add(2)
+    s := NewStore(src, &StoreOptions{WriteQueueSize: 1}, tier)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancelled := make(chan error, 1)
+    go func() {
+        _, err := s.GetEvent(ctx, e.ID())
+        cancelled <- err
+    }()
+    if !waitFor(func() bool { return atomic.LoadInt32(&src.calls) == 1 }) {
+        t.Fatal("the source was never called")
+    }
+
+    other := make(chan error, 1)
+    go func() {
+        got, err := s.GetEvent(context.Background(), e.ID())
+        if err == nil && got != e {
+            t.Errorf("got a different event")
+        }
+        other <- err
+    }()
+    tier.arrivals.Wait()
+    time.Sleep(20 * time.Millisecond)
+
+    cancel()
+    if err := <-cancelled; err != context.Canceled {
+        t.Errorf("cancelled read: got error %v, want context.Canceled", err)
+    }
+
+    close(src.release)
+    if err := <-other; err != nil {
+        t.Errorf("other read: got error %v", err)
+    }
+    if calls := atomic.LoadInt32(&src.calls); calls != 1 {
+        t.Errorf("got %d source calls, want 1", calls)
+    }
+}
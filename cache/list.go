@@ -36,6 +36,13 @@ func (l *list) len() int {
 }
 
 func (l *list) remove(k event.EventID) {
+    for l.head != nil && l.head.key == k {
+        l.head = l.head.next
+    }
+    if l.head == nil {
+        return
+    }
+
     prev := l.head
     n := prev.next
     for n != nil {
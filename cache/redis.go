@@ -0,0 +1,261 @@
+package cache
+
+import (
+    "context"
+    "errors"
+    "log"
+    "time"
+
+    "github.com/go-redis/redis"
+    "github.com/tobyjsullivan/event-log-reader/breaker"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+const DEFAULT_PROBE_INTERVAL = 5 * time.Second
+
+var ErrRedisUnavailable = errors.New("redis is unavailable")
+
+// RedisTier caches events in a shared Redis. Consecutive failures open a circuit
+// breaker, after which Redis is skipped (every read is a miss and writes are dropped)
+// until the health probe reaches it again.
+type RedisTier struct {
+    client redis.UniversalClient
+    logger *log.Logger
+    keyPrefix string
+    ttl time.Duration
+    breaker *breaker.Breaker
+    counters Counters
+}
+
+type RedisTierOptions struct {
+    // KeyPrefix namespaces the cache keys so the Redis can be shared with other services.
+    KeyPrefix string
+    // TTL expires cached events after the given duration. Zero keeps them forever.
+    TTL time.Duration
+    // FailureThreshold is how many consecutive errors mark Redis as unhealthy.
+    FailureThreshold int
+    // ProbeInterval is how often an unhealthy Redis is pinged to check for recovery.
+    ProbeInterval time.Duration
+}
+
+func NewRedisTier(client redis.UniversalClient, opts *RedisTierOptions, logger *log.Logger) *RedisTier {
+    t := &RedisTier{
+        client: client,
+        logger: logger,
+        keyPrefix: opts.KeyPrefix,
+        ttl: opts.TTL,
+        breaker: breaker.New(opts.FailureThreshold, 0),
+    }
+
+    err := client.Ping().Err()
+    if err != nil {
+        logger.Println("Redis is unavailable; skipping it until it recovers.", err.Error())
+        t.breaker.Trip()
+    }
+
+    interval := opts.ProbeInterval
+    if interval <= 0 {
+        interval = DEFAULT_PROBE_INTERVAL
+    }
+    go t.probe(interval)
+
+    return t
+}
+
+func (t *RedisTier) Name() string {
+    return "redis"
+}
+
+func (t *RedisTier) Get(ctx context.Context, id event.EventID) (*event.Event, bool) {
+    if ctx.Err() != nil {
+        return nil, false
+    }
+    if !t.breaker.Allow() {
+        t.counters.Skip()
+        return nil, false
+    }
+
+    res, err := t.client.Get(t.key(id)).Bytes()
+    if err == redis.Nil {
+        t.breaker.Success()
+        t.counters.Miss()
+        return nil, false
+    } else if err != nil {
+        t.failure(err)
+        t.counters.Miss()
+        return nil, false
+    }
+    t.breaker.Success()
+
+    e, err := UnmarshalEvent(res)
+    if err != nil {
+        t.logger.Println("Error deserializing redis result.", err.Error())
+        t.counters.Miss()
+        return nil, false
+    }
+    t.counters.Hit()
+    return e, true
+}
+
+// GetMulti uses MGET on a single server. Cluster and Ring deployments spread keys
+// across nodes, so there the GETs are pipelined instead.
+func (t *RedisTier) GetMulti(ctx context.Context, ids []event.EventID) []*event.Event {
+    out := make([]*event.Event, len(ids))
+    if ctx.Err() != nil {
+        return out
+    }
+    if !t.breaker.Allow() {
+        for range ids {
+            t.counters.Skip()
+        }
+        return out
+    }
+
+    keys := make([]string, len(ids))
+    for i, id := range ids {
+        keys[i] = t.key(id)
+    }
+
+    values, err := t.mget(keys)
+    if err != nil {
+        t.failure(err)
+        for range ids {
+            t.counters.Miss()
+        }
+        return out
+    }
+    t.breaker.Success()
+
+    for i, v := range values {
+        if v == nil {
+            t.counters.Miss()
+            continue
+        }
+
+        e, err := UnmarshalEvent(v)
+        if err != nil {
+            t.logger.Println("Error deserializing redis result.", err.Error())
+            t.counters.Miss()
+            continue
+        }
+        out[i] = e
+        t.counters.Hit()
+    }
+    return out
+}
+
+// mget returns the value of each key, or nil where the key does not exist.
+func (t *RedisTier) mget(keys []string) ([][]byte, error) {
+    out := make([][]byte, len(keys))
+
+    if _, ok := t.client.(*redis.Client); ok {
+        res, err := t.client.MGet(keys...).Result()
+        if err != nil {
+            return nil, err
+        }
+        for i, v := range res {
+            if s, ok := v.(string); ok {
+                out[i] = []byte(s)
+            }
+        }
+        return out, nil
+    }
+
+    pipe := t.client.Pipeline()
+    cmds := make([]*redis.StringCmd, len(keys))
+    for i, key := range keys {
+        cmds[i] = pipe.Get(key)
+    }
+    _, err := pipe.Exec()
+    if err != nil && err != redis.Nil {
+        return nil, err
+    }
+    for i, cmd := range cmds {
+        b, err := cmd.Bytes()
+        if err == nil {
+            out[i] = b
+        }
+    }
+    return out, nil
+}
+
+func (t *RedisTier) Add(e *event.Event) {
+    if !t.breaker.Allow() {
+        t.counters.Skip()
+        return
+    }
+
+    err := t.client.Set(t.key(e.ID()), MarshalEvent(e), t.ttl).Err()
+    if err != nil {
+        t.failure(err)
+        return
+    }
+    t.breaker.Success()
+}
+
+func (t *RedisTier) Remove(id event.EventID) (bool, error) {
+    if !t.breaker.Allow() {
+        return false, ErrRedisUnavailable
+    }
+
+    n, err := t.client.Del(t.key(id)).Result()
+    if err != nil {
+        t.failure(err)
+        return false, err
+    }
+    t.breaker.Success()
+
+    if n > 0 {
+        t.counters.Evict()
+    }
+    return n > 0, nil
+}
+
+func (t *RedisTier) Stats() Stats {
+    return t.counters.Snapshot()
+}
+
+// Client and Breaker let other Redis users share the tier's connection settings and
+// health tracking.
+func (t *RedisTier) Client() redis.UniversalClient {
+    return t.client
+}
+
+func (t *RedisTier) Breaker() *breaker.Breaker {
+    return t.breaker
+}
+
+// Healthy reports whether Redis is currently in use.
+func (t *RedisTier) Healthy() bool {
+    return t.breaker.State() == breaker.Closed
+}
+
+func (t *RedisTier) key(id event.EventID) string {
+    return t.keyPrefix + id.String()
+}
+
+func (t *RedisTier) failure(err error) {
+    t.logger.Println("Redis error:", err.Error())
+
+    wasHealthy := t.Healthy()
+    t.breaker.Failure()
+    if wasHealthy && !t.Healthy() {
+        t.logger.Println("Redis marked unhealthy; skipping it until it recovers.")
+    }
+}
+
+func (t *RedisTier) probe(interval time.Duration) {
+    for range time.Tick(interval) {
+        if t.Healthy() {
+            continue
+        }
+
+        err := t.client.Ping().Err()
+        if err != nil {
+            continue
+        }
+
+        t.logger.Println("Redis is reachable again; re-enabling it.")
+        t.breaker.Reset()
+    }
+}
@@ -0,0 +1,48 @@
+package cache
+
+import "sync/atomic"
+
+type Stats struct {
+    Hits uint64 `json:"hits"`
+    Misses uint64 `json:"misses"`
+    Evictions uint64 `json:"evictions"`
+    // Skipped counts operations not attempted because the tier was unavailable.
+    Skipped uint64 `json:"skipped,omitempty"`
+}
+
+// Counters tracks Stats for a cache tier. It is safe for concurrent use.
+type Counters struct {
+    hits uint64
+    misses uint64
+    evictions uint64
+    skipped uint64
+}
+
+func (c *Counters) Hit() {
+    atomic.AddUint64(&c.hits, 1)
+}
+
+func (c *Counters) Miss() {
+    atomic.AddUint64(&c.misses, 1)
+}
+
+func (c *Counters) Evict() {
+    atomic.AddUint64(&c.evictions, 1)
+}
+
+func (c *Counters) EvictN(n int) {
+    atomic.AddUint64(&c.evictions, uint64(n))
+}
+
+func (c *Counters) Skip() {
+    atomic.AddUint64(&c.skipped, 1)
+}
+
+func (c *Counters) Snapshot() Stats {
+    return Stats{
+        Hits: atomic.LoadUint64(&c.hits),
+        Misses: atomic.LoadUint64(&c.misses),
+        Evictions: atomic.LoadUint64(&c.evictions),
+        Skipped: atomic.LoadUint64(&c.skipped),
+    }
+}
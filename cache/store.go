@@ -0,0 +1,302 @@
+package cache
+
+import (
+    "context"
+    "fmt"
+    "sync"
+    "sync/atomic"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// WritePolicy decides which tiers are filled after a read.
+type WritePolicy int
+
+const (
+    // WriteBack fills every tier above the one that answered. Events fetched from
+    // the source are written to all tiers.
+    WriteBack WritePolicy = iota
+    // WriteOnFetch only fills tiers with events fetched from the source. Hits in a
+    // lower tier are not copied upwards.
+    WriteOnFetch
+    // WriteNone never writes to the tiers; they are treated as read-only.
+    WriteNone
+)
+
+func ParseWritePolicy(s string) (WritePolicy, error) {
+    switch s {
+    case "", "write-back":
+        return WriteBack, nil
+    case "write-on-fetch":
+        return WriteOnFetch, nil
+    case "none":
+        return WriteNone, nil
+    }
+    return WriteBack, fmt.Errorf("unknown cache write policy %q", s)
+}
+
+// Store reads events through an ordered list of tiers, fastest first, falling back to
+// the source when all of them miss. Every event read is checked against the ID it was
+// requested by. Tiers are filled asynchronously from a bounded queue; writes are
+// dropped rather than queued without limit when it is full.
+type Store struct {
+    tiers []Tier
+    source Source
+    policy WritePolicy
+    writes chan *fillJob
+    dropped uint64
+    fetchConcurrency int
+    flights flightGroup
+    fetches uint64
+    shared uint64
+    tierMismatches []uint64
+    sourceMismatches uint64
+}
+
+type StoreOptions struct {
+    Policy WritePolicy
+    // WriteQueueSize bounds the number of pending tier writes.
+    WriteQueueSize int
+    // WriteWorkers is the number of goroutines draining the write queue.
+    WriteWorkers int
+    // FetchConcurrency bounds the concurrent source fetches made by GetEvents.
+    FetchConcurrency int
+}
+
+type fillJob struct {
+    e *event.Event
+    tiers []Tier
+}
+
+// SourceStats counts reads that missed every tier. Fetches went to the source; shared
+// reads waited on a concurrent fetch of the same event instead.
+type SourceStats struct {
+    Fetches uint64 `json:"fetches"`
+    Shared uint64 `json:"shared"`
+}
+
+// WriteQueueStats describes the asynchronous tier write queue.
+type WriteQueueStats struct {
+    Pending int `json:"pending"`
+    Capacity int `json:"capacity"`
+    Dropped uint64 `json:"dropped"`
+}
+
+func NewStore(source Source, opts *StoreOptions, tiers ...Tier) *Store {
+    s := &Store{
+        tiers: tiers,
+        source: source,
+        policy: opts.Policy,
+        writes: make(chan *fillJob, opts.WriteQueueSize),
+        fetchConcurrency: opts.FetchConcurrency,
+        tierMismatches: make([]uint64, len(tiers)),
+    }
+    if s.fetchConcurrency < 1 {
+        s.fetchConcurrency = 1
+    }
+
+    workers := opts.WriteWorkers
+    if workers < 1 {
+        workers = 1
+    }
+    for i := 0; i < workers; i++ {
+        go s.writer()
+    }
+
+    return s
+}
+
+func (s *Store) Tiers() []Tier {
+    return s.tiers
+}
+
+// Tier returns the configured tier with the given name.
+func (s *Store) Tier(name string) (Tier, bool) {
+    for _, t := range s.tiers {
+        if t.Name() == name {
+            return t, true
+        }
+    }
+    return nil, false
+}
+
+func (s *Store) GetEvent(ctx context.Context, id event.EventID) (*event.Event, error) {
+    for i, t := range s.tiers {
+        if e, ok := t.Get(ctx, id); ok && s.verifyHit(i, id, e) {
+            if s.policy == WriteBack && i > 0 {
+                s.fill(e, s.tiers[:i])
+            }
+            return e, nil
+        }
+    }
+
+    return s.fetch(ctx, id)
+}
+
+// GetEvents loads several events at once. Tiers that implement MultiTier are queried
+// in a single round trip and the remaining misses are fetched from the source
+// concurrently. The result is in the same order as ids.
+func (s *Store) GetEvents(ctx context.Context, ids []event.EventID) ([]*event.Event, error) {
+    out := make([]*event.Event, len(ids))
+    missing := make([]int, len(ids))
+    for i := range ids {
+        missing[i] = i
+    }
+
+    for i, t := range s.tiers {
+        if len(missing) == 0 {
+            break
+        }
+
+        wanted := make([]event.EventID, len(missing))
+        for j, idx := range missing {
+            wanted[j] = ids[idx]
+        }
+
+        found := getMulti(ctx, t, wanted)
+        stillMissing := missing[:0]
+        for j, idx := range missing {
+            e := found[j]
+            if e == nil || !s.verifyHit(i, ids[idx], e) {
+                stillMissing = append(stillMissing, idx)
+                continue
+            }
+
+            out[idx] = e
+            if s.policy == WriteBack && i > 0 {
+                s.fill(e, s.tiers[:i])
+            }
+        }
+        missing = stillMissing
+    }
+
+    if len(missing) == 0 {
+        return out, nil
+    }
+
+    // Stop starting fetches once one has failed.
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    var wg sync.WaitGroup
+    var mu sync.Mutex
+    var firstErr error
+    sem := make(chan struct{}, s.fetchConcurrency)
+    for _, idx := range missing {
+        select {
+        case sem <- struct{}{}:
+        case <-ctx.Done():
+        }
+        if ctx.Err() != nil {
+            break
+        }
+
+        wg.Add(1)
+        go func(idx int) {
+            defer wg.Done()
+            defer func() { <-sem }()
+
+            e, err := s.fetch(ctx, ids[idx])
+            if err != nil {
+                mu.Lock()
+                if firstErr == nil {
+                    firstErr = err
+                }
+                mu.Unlock()
+                cancel()
+                return
+            }
+            out[idx] = e
+        }(idx)
+    }
+    wg.Wait()
+
+    if firstErr == nil {
+        firstErr = ctx.Err()
+    }
+    if firstErr != nil {
+        return nil, firstErr
+    }
+    return out, nil
+}
+
+// fetch loads an event from the source, sharing the request with any concurrent
+// fetch of the same event. The shared request is not tied to any one caller's
+// context, so a caller giving up doesn't fail the others; the source applies its own
+// deadline.
+func (s *Store) fetch(ctx context.Context, id event.EventID) (*event.Event, error) {
+    e, err, shared := s.flights.do(ctx, id, func() (*event.Event, error) {
+        atomic.AddUint64(&s.fetches, 1)
+        e, err := s.source.GetEvent(context.Background(), id)
+        if err != nil {
+            return nil, err
+        }
+        err = s.verifyFetch(id, e)
+        if err != nil {
+            return nil, err
+        }
+
+        if s.policy != WriteNone {
+            s.fill(e, s.tiers)
+        }
+        return e, nil
+    })
+    if shared {
+        atomic.AddUint64(&s.shared, 1)
+    }
+
+    return e, err
+}
+
+// Remove evicts the event from every tier, carrying on past tiers that fail so one
+// unavailable tier doesn't keep the event in the others. It returns whether each tier
+// held the event and the errors of the tiers that failed, both keyed by tier name.
+func (s *Store) Remove(id event.EventID) (map[string]bool, map[string]error) {
+    out := make(map[string]bool, len(s.tiers))
+    errs := make(map[string]error)
+    for _, t := range s.tiers {
+        ok, err := t.Remove(id)
+        if err != nil {
+            errs[t.Name()] = err
+            continue
+        }
+        out[t.Name()] = ok
+    }
+    return out, errs
+}
+
+func (s *Store) SourceStats() SourceStats {
+    return SourceStats{
+        Fetches: atomic.LoadUint64(&s.fetches),
+        Shared: atomic.LoadUint64(&s.shared),
+    }
+}
+
+func (s *Store) WriteQueueStats() WriteQueueStats {
+    return WriteQueueStats{
+        Pending: len(s.writes),
+        Capacity: cap(s.writes),
+        Dropped: atomic.LoadUint64(&s.dropped),
+    }
+}
+
+// fill queues the event to be written to the given tiers without blocking.
+func (s *Store) fill(e *event.Event, tiers []Tier) {
+    if len(tiers) == 0 {
+        return
+    }
+
+    select {
+    case s.writes <- &fillJob{e: e, tiers: tiers}:
+    default:
+        atomic.AddUint64(&s.dropped, 1)
+    }
+}
+
+func (s *Store) writer() {
+    for job := range s.writes {
+        for _, t := range job.tiers {
+            t.Add(job.e)
+        }
+    }
+}
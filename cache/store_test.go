@@ -0,0 +1,67 @@
+package cache
+
+import (
+    "context"
+    "errors"
+    "testing"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+func testEvent(data string) *event.Event {
+    return &event.Event{
+        Type: "TestEvent",
+        Data: event.EventData(data),
+    }
+}
+
+var errTierDown = errors.New("tier is down")
+
+// brokenTier is a tier whose backend is unavailable, like a Redis tier with its
+// breaker open.
+type brokenTier struct {
+    name string
+}
+
+func (t *brokenTier) Name() string {
+    return t.name
+}
+
+func (t *brokenTier) Get(ctx context.Context, id event.EventID) (*event.Event, bool) {
+    return nil, false
+}
+
+func (t *brokenTier) Add(e *event.Event) {}
+
+func (t *brokenTier) Remove(id event.EventID) (bool, error) {
+    return false, errTierDown
+}
+
+func (t *brokenTier) Stats() Stats {
+    return Stats{}
+}
+
+func TestStoreRemoveTriesEveryTier(t *testing.T) {
+    e := testEvent("remove me")
+    id := e.ID()
+
+    first := New(10)
+    last := New(10)
+    first.Add(e)
+    last.Add(e)
+    s := NewStore(nil, &StoreOptions{}, first, &brokenTier{name: "broken"}, last)
+
+    removed, errs := s.Remove(id)
+    if errs["broken"] != errTierDown || len(errs) != 1 {
+        t.Errorf("got errors %v, want only the broken tier's", errs)
+    }
+    if !removed["memory"] {
+        t.Errorf("got %v, want the event removed from memory", removed)
+    }
+    if _, ok := removed["broken"]; ok {
+        t.Errorf("got %v, want no result for the broken tier", removed)
+    }
+    if first.Len() != 0 || last.Len() != 0 {
+        t.Errorf("event still held by a tier after the broken one")
+    }
+}
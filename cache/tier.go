@@ -0,0 +1,43 @@
+package cache
+
+import (
+    "context"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// Tier is a single level of a tiered Store, such as process memory or Redis.
+type Tier interface {
+    Name() string
+    Get(ctx context.Context, id event.EventID) (*event.Event, bool)
+    Add(e *event.Event)
+    Remove(id event.EventID) (bool, error)
+    Stats() Stats
+}
+
+// MultiTier is a Tier that can look up several events in one round trip.
+type MultiTier interface {
+    Tier
+    // GetMulti returns the events in the same order as ids, with nil for each miss.
+    GetMulti(ctx context.Context, ids []event.EventID) []*event.Event
+}
+
+func getMulti(ctx context.Context, t Tier, ids []event.EventID) []*event.Event {
+    if m, ok := t.(MultiTier); ok {
+        return m.GetMulti(ctx, ids)
+    }
+
+    out := make([]*event.Event, len(ids))
+    for i, id := range ids {
+        if e, ok := t.Get(ctx, id); ok {
+            out[i] = e
+        }
+    }
+    return out
+}
+
+// Source is where a Store loads events from once every tier has missed. A Store is
+// itself a Source, so stores can be layered.
+type Source interface {
+    GetEvent(ctx context.Context, id event.EventID) (*event.Event, error)
+}
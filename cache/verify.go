@@ -0,0 +1,62 @@
+package cache
+
+import (
+    "fmt"
+    "sync/atomic"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// MismatchError means the source returned an event whose content doesn't hash to the
+// requested ID.
+type MismatchError struct {
+    Want event.EventID
+    Got event.EventID
+}
+
+func (e *MismatchError) Error() string {
+    return fmt.Sprintf("upstream returned event %s for %s", e.Got.String(), e.Want.String())
+}
+
+// IntegrityStats counts events that didn't hash to the ID they were read under. Tier
+// mismatches are keyed by tier name and were evicted; source mismatches failed the
+// read.
+type IntegrityStats struct {
+    Tiers map[string]uint64 `json:"tiers"`
+    Source uint64 `json:"source"`
+}
+
+// verifyHit reports whether e, read from tier i, is the event id. A corrupt entry is
+// evicted from the tier so the next read falls through to a good copy.
+func (s *Store) verifyHit(i int, id event.EventID, e *event.Event) bool {
+    if e.ID() == id {
+        return true
+    }
+
+    atomic.AddUint64(&s.tierMismatches[i], 1)
+    // Best effort; an unreachable tier is evicted from the next time it answers.
+    s.tiers[i].Remove(id)
+    return false
+}
+
+// verifyFetch returns an error if e, fetched from the source, is not the event id.
+func (s *Store) verifyFetch(id event.EventID, e *event.Event) error {
+    got := e.ID()
+    if got == id {
+        return nil
+    }
+
+    atomic.AddUint64(&s.sourceMismatches, 1)
+    return &MismatchError{Want: id, Got: got}
+}
+
+func (s *Store) IntegrityStats() IntegrityStats {
+    out := IntegrityStats{
+        Tiers: make(map[string]uint64, len(s.tiers)),
+        Source: atomic.LoadUint64(&s.sourceMismatches),
+    }
+    for i, t := range s.tiers {
+        out.Tiers[t.Name()] = atomic.LoadUint64(&s.tierMismatches[i])
+    }
+    return out
+}
@@ -0,0 +1,84 @@
+package chain
+
+import (
+    "context"
+    "sync"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// LinkIndex records each event's previous event ID so that a chain of IDs can be
+// resolved without loading every event in full.
+type LinkIndex interface {
+    // Add records that prev is the event before id.
+    Add(id, prev event.EventID)
+
+    // Walk follows indexed links back from head and returns the IDs visited, starting
+    // with head itself. It stops before stop or the zero ID, after an event whose link
+    // is not indexed, or once max IDs have been collected.
+    Walk(ctx context.Context, head, stop event.EventID, max int) []event.EventID
+}
+
+// MemoryLinks is a LinkIndex held in process memory. Once it reaches its capacity
+// the oldest links are overwritten.
+type MemoryLinks struct {
+    maxSize int
+
+    mu sync.RWMutex
+    links map[event.EventID]event.EventID
+    order []event.EventID
+    next int
+}
+
+func NewMemoryLinks(maxSize int) *MemoryLinks {
+    return &MemoryLinks{
+        maxSize: maxSize,
+        links: make(map[event.EventID]event.EventID),
+    }
+}
+
+func (l *MemoryLinks) Add(id, prev event.EventID) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    if _, ok := l.links[id]; ok {
+        return
+    }
+
+    if len(l.order) < l.maxSize {
+        l.order = append(l.order, id)
+    } else if len(l.order) > 0 {
+        delete(l.links, l.order[l.next])
+        l.order[l.next] = id
+        l.next = (l.next + 1) % len(l.order)
+    } else {
+        return
+    }
+    l.links[id] = prev
+}
+
+func (l *MemoryLinks) Walk(ctx context.Context, head, stop event.EventID, max int) []event.EventID {
+    l.mu.RLock()
+    defer l.mu.RUnlock()
+
+    zero := event.EventID{}
+    out := []event.EventID{head}
+    for len(out) < max {
+        prev, ok := l.links[head]
+        if !ok || prev == stop || prev == zero {
+            break
+        }
+        out = append(out, prev)
+        head = prev
+    }
+    return out
+}
+
+// NoLinks disables the link index.
+type NoLinks struct{}
+
+func (NoLinks) Add(id, prev event.EventID) {}
+
+func (NoLinks) Walk(ctx context.Context, head, stop event.EventID, max int) []event.EventID {
+    return []event.EventID{head}
+}
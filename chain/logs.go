@@ -0,0 +1,87 @@
+package chain
+
+import (
+    "sync"
+
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// LogIndex maps events to the logs whose history includes them. It is filled as the
+// chains of logs are resolved, so it only knows logs this instance has read. Once it
+// holds maxSize entries, the oldest are overwritten.
+type LogIndex struct {
+    mu sync.RWMutex
+    logs map[event.EventID][]eventLog.LogID
+    order []logEntry
+    next int
+}
+
+type logEntry struct {
+    id event.EventID
+    logId eventLog.LogID
+}
+
+func NewLogIndex(maxSize int) *LogIndex {
+    return &LogIndex{
+        logs: make(map[event.EventID][]eventLog.LogID),
+        order: make([]logEntry, 0, maxSize),
+    }
+}
+
+// Add records that the events are in the log's history.
+func (x *LogIndex) Add(logId eventLog.LogID, ids []event.EventID) {
+    x.mu.Lock()
+    defer x.mu.Unlock()
+
+    for _, id := range ids {
+        if x.has(id, logId) {
+            continue
+        }
+
+        if len(x.order) < cap(x.order) {
+            x.order = append(x.order, logEntry{id: id, logId: logId})
+        } else if len(x.order) > 0 {
+            x.remove(x.order[x.next])
+            x.order[x.next] = logEntry{id: id, logId: logId}
+            x.next = (x.next + 1) % len(x.order)
+        } else {
+            return
+        }
+        x.logs[id] = append(x.logs[id], logId)
+    }
+}
+
+// Logs returns the logs known to include the event.
+func (x *LogIndex) Logs(id event.EventID) []eventLog.LogID {
+    x.mu.RLock()
+    defer x.mu.RUnlock()
+
+    return append([]eventLog.LogID(nil), x.logs[id]...)
+}
+
+// Callers must hold x.mu.
+func (x *LogIndex) has(id event.EventID, logId eventLog.LogID) bool {
+    for _, l := range x.logs[id] {
+        if l == logId {
+            return true
+        }
+    }
+    return false
+}
+
+// Callers must hold x.mu for writing.
+func (x *LogIndex) remove(entry logEntry) {
+    logs := x.logs[entry.id]
+    for i, l := range logs {
+        if l == entry.logId {
+            logs = append(logs[:i], logs[i + 1:]...)
+            break
+        }
+    }
+    if len(logs) == 0 {
+        delete(x.logs, entry.id)
+    } else {
+        x.logs[entry.id] = logs
+    }
+}
@@ -0,0 +1,207 @@
+package chain
+
+import (
+    "context"
+    "log"
+    "time"
+
+    "github.com/go-redis/redis"
+    "github.com/tobyjsullivan/event-log-reader/breaker"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+const (
+    DEFAULT_REDIS_LINKS_TTL = 24 * time.Hour
+    DEFAULT_REDIS_LINKS_QUEUE_SIZE = 10000
+    redisLinksBatchSize = 100
+)
+
+// walkScript follows links server-side so a whole run of the chain resolves in one
+// round trip. It builds its keys from ARGV, which only a single server allows, so
+// Cluster and Ring deployments walk client-side instead.
+var walkScript = redis.NewScript(`
+local ids = {ARGV[1]}
+local cur = ARGV[1]
+local max = tonumber(ARGV[3])
+while #ids < max do
+    local prev = redis.call('GET', ARGV[5] .. cur)
+    if not prev or prev == ARGV[2] or prev == ARGV[4] then
+        break
+    end
+    ids[#ids + 1] = prev
+    cur = prev
+end
+return ids
+`)
+
+// RedisLinks is a LinkIndex stored in Redis as one expiring key per event, holding the
+// hex previous event ID, so it is shared between processes and survives restarts.
+// Links are also kept in a local MemoryLinks, which answers walks first and stands in
+// for Redis while its breaker is open. Writes to Redis are queued and made in the
+// background; links are dropped when the queue is full.
+type RedisLinks struct {
+    client redis.UniversalClient
+    breaker *breaker.Breaker
+    local *MemoryLinks
+    keyPrefix string
+    ttl time.Duration
+    logger *log.Logger
+    queue chan redisLink
+}
+
+type RedisLinksOptions struct {
+    // KeyPrefix namespaces the link keys so the Redis can be shared with other services.
+    KeyPrefix string
+    // TTL expires links after the given duration. Defaults to DEFAULT_REDIS_LINKS_TTL.
+    TTL time.Duration
+    // QueueSize bounds the links waiting to be written.
+    QueueSize int
+}
+
+type redisLink struct {
+    id event.EventID
+    prev event.EventID
+}
+
+// NewRedisLinks shares the client and breaker, typically those of the Redis cache
+// tier, so that link traffic obeys the same timeouts and is skipped while Redis is
+// unhealthy.
+func NewRedisLinks(client redis.UniversalClient, b *breaker.Breaker, local *MemoryLinks, opts *RedisLinksOptions, logger *log.Logger) *RedisLinks {
+    ttl := opts.TTL
+    if ttl <= 0 {
+        ttl = DEFAULT_REDIS_LINKS_TTL
+    }
+    queueSize := opts.QueueSize
+    if queueSize <= 0 {
+        queueSize = DEFAULT_REDIS_LINKS_QUEUE_SIZE
+    }
+
+    l := &RedisLinks{
+        client: client,
+        breaker: b,
+        local: local,
+        keyPrefix: opts.KeyPrefix + "link:",
+        ttl: ttl,
+        logger: logger,
+        queue: make(chan redisLink, queueSize),
+    }
+    go l.writer()
+    return l
+}
+
+func (l *RedisLinks) Add(id, prev event.EventID) {
+    l.local.Add(id, prev)
+
+    select {
+    case l.queue <- redisLink{id: id, prev: prev}:
+    default:
+    }
+}
+
+func (l *RedisLinks) Walk(ctx context.Context, head, stop event.EventID, max int) []event.EventID {
+    out := l.local.Walk(ctx, head, stop, max)
+    if len(out) >= max || ctx.Err() != nil || !l.breaker.Allow() {
+        return out
+    }
+
+    // Carry on from where the local links ran out.
+    last := out[len(out) - 1]
+    rest, err := l.walk(last, stop, max - len(out) + 1)
+    if err != nil {
+        l.failure(err)
+        return out
+    }
+    l.breaker.Success()
+
+    for _, id := range rest[1:] {
+        l.local.Add(last, id)
+        last = id
+    }
+    return append(out, rest[1:]...)
+}
+
+// walk returns the IDs from head back, starting with head itself.
+func (l *RedisLinks) walk(head, stop event.EventID, max int) ([]event.EventID, error) {
+    zero := event.EventID{}
+
+    if _, ok := l.client.(*redis.Client); ok {
+        res, err := walkScript.Run(l.client, nil, head.String(), stop.String(), max, zero.String(), l.keyPrefix).Result()
+        if err != nil {
+            return nil, err
+        }
+
+        values, _ := res.([]interface{})
+        out := make([]event.EventID, 0, len(values))
+        for _, v := range values {
+            s, _ := v.(string)
+            var id event.EventID
+            if len(s) != len(id) * 2 || id.Parse(s) != nil {
+                break
+            }
+            out = append(out, id)
+        }
+        if len(out) == 0 || out[0] != head {
+            return []event.EventID{head}, nil
+        }
+        return out, nil
+    }
+
+    out := []event.EventID{head}
+    for len(out) < max {
+        s, err := l.client.Get(l.key(head)).Result()
+        if err == redis.Nil {
+            break
+        } else if err != nil {
+            return nil, err
+        }
+
+        var prev event.EventID
+        if len(s) != len(prev) * 2 || prev.Parse(s) != nil || prev == stop || prev == zero {
+            break
+        }
+        out = append(out, prev)
+        head = prev
+    }
+    return out, nil
+}
+
+// writer sets queued links in pipelined batches.
+func (l *RedisLinks) writer() {
+    batch := make([]redisLink, 0, redisLinksBatchSize)
+    for link := range l.queue {
+        batch = append(batch[:0], link)
+    drain:
+        for len(batch) < redisLinksBatchSize {
+            select {
+            case link = <-l.queue:
+                batch = append(batch, link)
+            default:
+                break drain
+            }
+        }
+
+        if !l.breaker.Allow() {
+            continue
+        }
+
+        pipe := l.client.Pipeline()
+        for _, link := range batch {
+            pipe.Set(l.key(link.id), link.prev.String(), l.ttl)
+        }
+        _, err := pipe.Exec()
+        if err != nil {
+            l.failure(err)
+            continue
+        }
+        l.breaker.Success()
+    }
+}
+
+func (l *RedisLinks) key(id event.EventID) string {
+    return l.keyPrefix + id.String()
+}
+
+func (l *RedisLinks) failure(err error) {
+    l.logger.Println("Error using event links in Redis.", err.Error())
+    l.breaker.Failure()
+}
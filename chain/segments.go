@@ -0,0 +1,176 @@
+package chain
+
+import (
+    linked "container/list"
+    "sync"
+
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// Segment is the resolved chain of a log up to a known head, oldest event first. It
+// only ever grows at the head, so positions stay valid as it is extended.
+type Segment struct {
+    mu sync.RWMutex
+    head event.EventID
+    ids []event.EventID
+    pos map[event.EventID]int
+}
+
+// NewSegment creates a segment from a full chain, given newest first.
+func NewSegment(head event.EventID, ids []event.EventID) *Segment {
+    s := &Segment{
+        ids: make([]event.EventID, 0, len(ids)),
+        pos: make(map[event.EventID]int, len(ids)),
+    }
+    s.extend(head, ids)
+    return s
+}
+
+func (s *Segment) Head() event.EventID {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    return s.head
+}
+
+func (s *Segment) Len() int {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    return len(s.ids)
+}
+
+// Position returns the 1-based position of id in the log.
+func (s *Segment) Position(id event.EventID) (int, bool) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    i, ok := s.pos[id]
+    return i + 1, ok
+}
+
+// Between returns the IDs after after, up to and including head, oldest first. The
+// zero ID stands for the start of the log. ok is false unless both are in the
+// segment with after preceding head.
+func (s *Segment) Between(after, head event.EventID) ([]event.EventID, bool) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    start := 0
+    if after != (event.EventID{}) {
+        i, ok := s.pos[after]
+        if !ok {
+            return nil, false
+        }
+        start = i + 1
+    }
+
+    end := 0
+    if head != (event.EventID{}) {
+        i, ok := s.pos[head]
+        if !ok {
+            return nil, false
+        }
+        end = i + 1
+    }
+
+    if start > end {
+        return nil, false
+    }
+
+    out := make([]event.EventID, end - start)
+    copy(out, s.ids[start:end])
+    return out, true
+}
+
+// Extend moves the segment's head forward. ids are the events between the current
+// head and the new one, newest first. It fails if the segment's head has moved since
+// the caller read it.
+func (s *Segment) Extend(from, head event.EventID, ids []event.EventID) bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.head != from {
+        return false
+    }
+    s.extend(head, ids)
+    return true
+}
+
+// Callers must hold s.mu for writing.
+func (s *Segment) extend(head event.EventID, ids []event.EventID) {
+    for i := len(ids) - 1; i >= 0; i-- {
+        s.pos[ids[i]] = len(s.ids)
+        s.ids = append(s.ids, ids[i])
+    }
+    s.head = head
+}
+
+// Segments remembers the resolved chain of recently read logs, so a read after the
+// head advances only needs to walk the new events. It holds up to a total number of
+// IDs across all segments, evicting the least recently used logs beyond that.
+type Segments struct {
+    maxIds int
+
+    mu sync.Mutex
+    lru *linked.List
+    logs map[eventLog.LogID]*linked.Element
+    size int
+}
+
+type segmentEntry struct {
+    logId eventLog.LogID
+    segment *Segment
+    // size is the segment's length when it was last accounted for.
+    size int
+}
+
+func NewSegments(maxIds int) *Segments {
+    return &Segments{
+        maxIds: maxIds,
+        lru: linked.New(),
+        logs: make(map[eventLog.LogID]*linked.Element),
+    }
+}
+
+func (s *Segments) Get(logId eventLog.LogID) (*Segment, bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    el, ok := s.logs[logId]
+    if !ok {
+        return nil, false
+    }
+    s.lru.MoveToFront(el)
+    return el.Value.(*segmentEntry).segment, true
+}
+
+// Put replaces the log's segment. Call it again after extending a segment so that its
+// new length counts towards the limit.
+func (s *Segments) Put(logId eventLog.LogID, seg *Segment) {
+    size := seg.Len()
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if el, ok := s.logs[logId]; ok {
+        entry := el.Value.(*segmentEntry)
+        s.size += size - entry.size
+        entry.segment = seg
+        entry.size = size
+        s.lru.MoveToFront(el)
+    } else {
+        s.logs[logId] = s.lru.PushFront(&segmentEntry{logId: logId, segment: seg, size: size})
+        s.size += size
+    }
+
+    // A segment longer than the limit on its own isn't kept either.
+    for s.size > s.maxIds {
+        oldest := s.lru.Back()
+        entry := oldest.Value.(*segmentEntry)
+        s.lru.Remove(oldest)
+        delete(s.logs, entry.logId)
+        s.size -= entry.size
+    }
+}
@@ -0,0 +1,287 @@
+package chain
+
+import (
+    "context"
+    "errors"
+    "sync"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+var errIndexTooSmall = errors.New("chain is too long for the skip index")
+
+// ErrWalkTooLong is returned when indexing an event would take more steps than the
+// index's walk limit.
+var ErrWalkTooLong = errors.New("too many unindexed events to resolve")
+
+// Resolver returns the ancestors of id, newest first, starting with the event before
+// it. It returns at least one ID, and as many more as it can resolve cheaply.
+type Resolver func(ctx context.Context, id event.EventID) ([]event.EventID, error)
+
+// SkipIndex keeps, for each indexed event, its height (its 1-based position counted
+// from the genesis event) and pointers to its ancestors at distances 1, 2, 4, ... 2^k.
+// With these, ancestor lookups and ancestry checks take O(log n) steps instead of a
+// walk. Events are indexed lazily, walking back to the nearest indexed ancestor.
+type SkipIndex struct {
+    resolve Resolver
+    maxSize int
+    maxWalk int
+
+    mu sync.RWMutex
+    nodes map[event.EventID]*skipNode
+    order []event.EventID
+    next int
+}
+
+type skipNode struct {
+    height uint64
+    // skips[k] is the ancestor 2^k events back. It is omitted past the genesis event.
+    skips []event.EventID
+}
+
+// NewSkipIndex creates an index holding up to maxSize events. Once full, the oldest
+// entries are dropped and rebuilt on demand. Lazily indexing an event resolves at
+// most maxWalk unindexed ancestors; zero means no limit.
+func NewSkipIndex(maxSize, maxWalk int, resolve Resolver) *SkipIndex {
+    return &SkipIndex{
+        resolve: resolve,
+        maxSize: maxSize,
+        maxWalk: maxWalk,
+        nodes: make(map[event.EventID]*skipNode),
+    }
+}
+
+// Known reports whether id is indexed. The zero ID, the parent of every genesis
+// event, is always known.
+func (s *SkipIndex) Known(id event.EventID) bool {
+    if id == (event.EventID{}) {
+        return true
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    _, ok := s.nodes[id]
+    return ok
+}
+
+// Extend indexes a run of the chain, given newest first with the previous ID of the
+// oldest event. It does nothing unless prev is itself indexed, and stops early if the
+// index has no room for an event.
+func (s *SkipIndex) Extend(ids []event.EventID, prev event.EventID) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, ok := s.node(prev); !ok {
+        return
+    }
+
+    for i := len(ids) - 1; i >= 0; i-- {
+        if !s.add(ids[i], prev) {
+            return
+        }
+        prev = ids[i]
+    }
+}
+
+// Height returns the 1-based position of id counted from the genesis event. The zero
+// ID has height 0.
+func (s *SkipIndex) Height(ctx context.Context, id event.EventID) (uint64, error) {
+    n, err := s.ensure(ctx, id)
+    if err != nil {
+        return 0, err
+    }
+    return n.height, nil
+}
+
+// Ancestor returns the event n steps before id, or the zero ID if that is before the
+// genesis event.
+func (s *SkipIndex) Ancestor(ctx context.Context, id event.EventID, n uint64) (event.EventID, error) {
+    node, err := s.ensure(ctx, id)
+    if err != nil {
+        return event.EventID{}, err
+    }
+    if n >= node.height {
+        return event.EventID{}, nil
+    }
+
+    for n > 0 {
+        // Take the longest jump that doesn't overshoot. Pointers may be missing where
+        // an ancestor was evicted when this event was indexed, so don't assume a level
+        // exists just because it would fit.
+        k := len(node.skips) - 1
+        for k > 0 && uint64(1) << uint(k) > n {
+            k--
+        }
+
+        id = node.skips[k]
+        n -= uint64(1) << uint(k)
+        node, err = s.ensure(ctx, id)
+        if err != nil {
+            return event.EventID{}, err
+        }
+    }
+    return id, nil
+}
+
+// IsAncestor reports whether a is head or one of its ancestors. The zero ID is an
+// ancestor of every event.
+func (s *SkipIndex) IsAncestor(ctx context.Context, a, head event.EventID) (bool, error) {
+    ha, err := s.Height(ctx, a)
+    if err != nil {
+        return false, err
+    }
+    hh, err := s.Height(ctx, head)
+    if err != nil {
+        return false, err
+    }
+    if ha > hh {
+        return false, nil
+    }
+
+    found, err := s.Ancestor(ctx, head, hh - ha)
+    if err != nil {
+        return false, err
+    }
+    return found == a, nil
+}
+
+// CommonAncestor returns the newest event that is a or one of its ancestors and also
+// b or one of its ancestors, or the zero ID if they share none. It brings both to the
+// same height and then binary searches for the shortest distance at which their
+// ancestors meet.
+func (s *SkipIndex) CommonAncestor(ctx context.Context, a, b event.EventID) (event.EventID, error) {
+    ha, err := s.Height(ctx, a)
+    if err != nil {
+        return event.EventID{}, err
+    }
+    hb, err := s.Height(ctx, b)
+    if err != nil {
+        return event.EventID{}, err
+    }
+
+    if ha > hb {
+        a, err = s.Ancestor(ctx, a, ha - hb)
+        ha = hb
+    } else if hb > ha {
+        b, err = s.Ancestor(ctx, b, hb - ha)
+    }
+    if err != nil {
+        return event.EventID{}, err
+    }
+    if a == b {
+        return a, nil
+    }
+
+    // Once the ancestors meet they stay equal, and at distance ha both are the zero ID.
+    lo, hi := uint64(1), ha
+    for lo < hi {
+        mid := lo + (hi - lo) / 2
+        x, err := s.Ancestor(ctx, a, mid)
+        if err != nil {
+            return event.EventID{}, err
+        }
+        y, err := s.Ancestor(ctx, b, mid)
+        if err != nil {
+            return event.EventID{}, err
+        }
+
+        if x == y {
+            hi = mid
+        } else {
+            lo = mid + 1
+        }
+    }
+    return s.Ancestor(ctx, a, lo)
+}
+
+// ensure returns the node for id, indexing it and any unindexed ancestors first.
+func (s *SkipIndex) ensure(ctx context.Context, id event.EventID) (*skipNode, error) {
+    s.mu.RLock()
+    n, ok := s.node(id)
+    s.mu.RUnlock()
+    if ok {
+        return n, nil
+    }
+
+    path := make([]event.EventID, 0)
+    cur := id
+    for !s.Known(cur) {
+        if s.maxWalk > 0 && len(path) >= s.maxWalk {
+            return nil, ErrWalkTooLong
+        }
+        path = append(path, cur)
+
+        prevs, err := s.resolve(ctx, cur)
+        if err != nil {
+            return nil, err
+        }
+        cur = prevs[0]
+        for _, prev := range prevs[1:] {
+            if s.Known(cur) || (s.maxWalk > 0 && len(path) >= s.maxWalk) {
+                break
+            }
+            path = append(path, cur)
+            cur = prev
+        }
+    }
+
+    s.Extend(path, cur)
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    n, _ = s.node(id)
+    if n == nil {
+        // Evicted again while building a chain longer than the index.
+        return nil, errIndexTooSmall
+    }
+    return n, nil
+}
+
+// Callers must hold s.mu.
+func (s *SkipIndex) node(id event.EventID) (*skipNode, bool) {
+    if id == (event.EventID{}) {
+        return &skipNode{}, true
+    }
+    n, ok := s.nodes[id]
+    return n, ok
+}
+
+// add indexes id and reports whether it is indexed afterwards, which it isn't if prev
+// isn't indexed or the index holds nothing. Callers must hold s.mu for writing.
+func (s *SkipIndex) add(id, prev event.EventID) bool {
+    if _, ok := s.nodes[id]; ok {
+        return true
+    }
+
+    parent, ok := s.node(prev)
+    if !ok {
+        return false
+    }
+    n := &skipNode{
+        height: parent.height + 1,
+    }
+    if prev != (event.EventID{}) {
+        n.skips = append(n.skips, prev)
+        for k := 0; ; k++ {
+            mid, ok := s.nodes[n.skips[k]]
+            if !ok || k >= len(mid.skips) {
+                break
+            }
+            n.skips = append(n.skips, mid.skips[k])
+        }
+    }
+
+    if s.maxSize <= 0 {
+        return false
+    }
+    if len(s.order) < s.maxSize {
+        s.order = append(s.order, id)
+    } else {
+        delete(s.nodes, s.order[s.next])
+        s.order[s.next] = id
+        s.next = (s.next + 1) % len(s.order)
+    }
+    s.nodes[id] = n
+    return true
+}
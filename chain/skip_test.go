@@ -0,0 +1,251 @@
+package chain
+
+import (
+    "context"
+    "testing"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// testChain is a set of event chains keyed by ID. Event IDs are made up: the first
+// byte names the branch and the next bytes the height.
+type testChain struct {
+    prev map[event.EventID]event.EventID
+    resolves int
+}
+
+func newTestChain() *testChain {
+    return &testChain{
+        prev: make(map[event.EventID]event.EventID),
+    }
+}
+
+func testID(branch byte, height int) event.EventID {
+    var id event.EventID
+    id[0] = branch
+    id[1] = byte(height >> 8)
+    id[2] = byte(height)
+    return id
+}
+
+// add appends events at heights from+1 to to onto parent, on the given branch, and
+// returns the last.
+func (c *testChain) add(branch byte, parent event.EventID, from, to int) event.EventID {
+    for h := from + 1; h <= to; h++ {
+        id := testID(branch, h)
+        c.prev[id] = parent
+        parent = id
+    }
+    return parent
+}
+
+// resolve returns a single previous ID, like a resolver with no link index.
+func (c *testChain) resolve(ctx context.Context, id event.EventID) ([]event.EventID, error) {
+    c.resolves++
+    return []event.EventID{c.prev[id]}, nil
+}
+
+func TestSkipPointers(t *testing.T) {
+    c := newTestChain()
+    head := c.add('a', event.EventID{}, 0, 40)
+
+    s := NewSkipIndex(1000, 0, c.resolve)
+    if _, err := s.Height(context.Background(), head); err != nil {
+        t.Fatal(err)
+    }
+
+    cases := []struct {
+        height int
+        want []int
+    }{
+        {1, nil},
+        {2, []int{1}},
+        {3, []int{2, 1}},
+        {5, []int{4, 3, 1}},
+        {8, []int{7, 6, 4}},
+        {9, []int{8, 7, 5, 1}},
+        {17, []int{16, 15, 13, 9, 1}},
+        {40, []int{39, 38, 36, 32, 24, 8}},
+    }
+    for _, tc := range cases {
+        n, ok := s.nodes[testID('a', tc.height)]
+        if !ok {
+            t.Fatalf("height %d: not indexed", tc.height)
+        }
+        if n.height != uint64(tc.height) {
+            t.Errorf("height %d: got height %d", tc.height, n.height)
+        }
+        if len(n.skips) != len(tc.want) {
+            t.Errorf("height %d: got %d skips, want %d", tc.height, len(n.skips), len(tc.want))
+            continue
+        }
+        for k, h := range tc.want {
+            if n.skips[k] != testID('a', h) {
+                t.Errorf("height %d: skips[%d] is not height %d", tc.height, k, h)
+            }
+        }
+    }
+}
+
+func TestAncestor(t *testing.T) {
+    c := newTestChain()
+    head := c.add('a', event.EventID{}, 0, 100)
+    s := NewSkipIndex(1000, 0, c.resolve)
+    ctx := context.Background()
+
+    cases := []struct {
+        n uint64
+        want event.EventID
+    }{
+        {0, head},
+        {1, testID('a', 99)},
+        {37, testID('a', 63)},
+        {99, testID('a', 1)},
+        {100, event.EventID{}},
+        {1000, event.EventID{}},
+    }
+    for _, tc := range cases {
+        got, err := s.Ancestor(ctx, head, tc.n)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got != tc.want {
+            t.Errorf("Ancestor(head, %d) = %s, want %s", tc.n, got.String(), tc.want.String())
+        }
+    }
+}
+
+func TestCommonAncestor(t *testing.T) {
+    c := newTestChain()
+    zero := event.EventID{}
+    trunk := c.add('a', zero, 0, 50)
+    fork := c.add('b', testID('a', 12), 12, 30)
+    late := c.add('c', testID('a', 49), 49, 52)
+    other := c.add('d', zero, 0, 20)
+
+    cases := []struct {
+        name string
+        a, b event.EventID
+        want event.EventID
+    }{
+        {"same event", trunk, trunk, trunk},
+        {"ancestor", trunk, testID('a', 7), testID('a', 7)},
+        {"descendant", testID('a', 7), trunk, testID('a', 7)},
+        {"fork", trunk, fork, testID('a', 12)},
+        {"fork reversed", fork, trunk, testID('a', 12)},
+        {"fork near head", trunk, late, testID('a', 49)},
+        {"two forks", fork, late, testID('a', 12)},
+        {"fork at genesis", testID('a', 1), testID('b', 13), testID('a', 1)},
+        {"unrelated", trunk, other, zero},
+        {"zero", trunk, zero, zero},
+    }
+    for _, tc := range cases {
+        s := NewSkipIndex(1000, 0, c.resolve)
+        got, err := s.CommonAncestor(context.Background(), tc.a, tc.b)
+        if err != nil {
+            t.Fatalf("%s: %s", tc.name, err.Error())
+        }
+        if got != tc.want {
+            t.Errorf("%s: got %s, want %s", tc.name, got.String(), tc.want.String())
+        }
+    }
+}
+
+func TestEnsureUsesResolvedRuns(t *testing.T) {
+    c := newTestChain()
+    head := c.add('a', event.EventID{}, 0, 100)
+
+    // Resolve up to ten ancestors at a time, as a link index would.
+    resolve := func(ctx context.Context, id event.EventID) ([]event.EventID, error) {
+        c.resolves++
+        out := make([]event.EventID, 0)
+        for len(out) < 10 {
+            id = c.prev[id]
+            out = append(out, id)
+            if id == (event.EventID{}) {
+                break
+            }
+        }
+        return out, nil
+    }
+
+    s := NewSkipIndex(1000, 0, resolve)
+    h, err := s.Height(context.Background(), head)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if h != 100 {
+        t.Errorf("got height %d, want 100", h)
+    }
+    if c.resolves != 10 {
+        t.Errorf("got %d resolver calls, want 10", c.resolves)
+    }
+}
+
+func TestEnsureWalkLimit(t *testing.T) {
+    c := newTestChain()
+    head := c.add('a', event.EventID{}, 0, 100)
+    ctx := context.Background()
+
+    s := NewSkipIndex(1000, 50, c.resolve)
+    _, err := s.Height(ctx, head)
+    if err != ErrWalkTooLong {
+        t.Fatalf("got error %v, want ErrWalkTooLong", err)
+    }
+
+    // Once the older part of the log is indexed, the rest is within the limit.
+    s.Extend(ancestors(c, testID('a', 60), 60), event.EventID{})
+    h, err := s.Height(ctx, head)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if h != 100 {
+        t.Errorf("got height %d, want 100", h)
+    }
+}
+
+// ancestors returns n IDs from id back, newest first.
+func ancestors(c *testChain, id event.EventID, n int) []event.EventID {
+    out := make([]event.EventID, 0, n)
+    for len(out) < n {
+        out = append(out, id)
+        id = c.prev[id]
+    }
+    return out
+}
+
+func TestSmallIndex(t *testing.T) {
+    c := newTestChain()
+    head := c.add('a', event.EventID{}, 0, 5)
+    ctx := context.Background()
+
+    // An index with no room can't hold anything, but mustn't panic either.
+    s := NewSkipIndex(0, 0, c.resolve)
+    if _, err := s.Height(ctx, head); err != errIndexTooSmall {
+        t.Errorf("no room: got error %v, want errIndexTooSmall", err)
+    }
+    s.Extend(ancestors(c, head, 5), event.EventID{})
+    if s.Known(head) {
+        t.Errorf("no room: head is indexed")
+    }
+
+    // Smaller indexes than the chain keep answering by rebuilding evicted entries.
+    for _, size := range []int{1, 2, 3} {
+        s := NewSkipIndex(size, 0, c.resolve)
+        h, err := s.Height(ctx, head)
+        if err != nil {
+            t.Fatalf("size %d: %s", size, err.Error())
+        }
+        if h != 5 {
+            t.Errorf("size %d: got height %d, want 5", size, h)
+        }
+
+        got, err := s.Ancestor(ctx, head, 3)
+        if err != nil {
+            t.Fatalf("size %d: %s", size, err.Error())
+        }
+        if got != testID('a', 2) {
+            t.Errorf("size %d: Ancestor(head, 3) = %s, want height 2", size, got.String())
+        }
+    }
+}
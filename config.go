@@ -0,0 +1,34 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "time"
+)
+
+func envInt64(name string, def int64) (int64, error) {
+    s := os.Getenv(name)
+    if s == "" {
+        return def, nil
+    }
+
+    v, err := strconv.ParseInt(s, 10, 64)
+    if err != nil {
+        return 0, fmt.Errorf("invalid %s: %s", name, err.Error())
+    }
+    return v, nil
+}
+
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+    s := os.Getenv(name)
+    if s == "" {
+        return def, nil
+    }
+
+    v, err := time.ParseDuration(s)
+    if err != nil {
+        return 0, fmt.Errorf("invalid %s: %s", name, err.Error())
+    }
+    return v, nil
+}
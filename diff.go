@@ -0,0 +1,109 @@
+package main
+
+import (
+    "encoding/base64"
+    "net/http"
+
+    "github.com/gorilla/mux"
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+    "github.com/tobyjsullivan/event-log-reader/source"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+type diffResponse struct {
+    LogID string `json:"logId"`
+    From string `json:"from"`
+    To string `json:"to"`
+    // CommonAncestor is the zero ID when the two share no history.
+    CommonAncestor string `json:"commonAncestor"`
+    // FromOnly and ToOnly are the events after the common ancestor on each side,
+    // oldest first.
+    FromOnly []*eventJson `json:"fromOnly"`
+    ToOnly []*eventJson `json:"toOnly"`
+}
+
+// diffHandler compares two points in history: two heads of forked logs, or a log at
+// two points in time. `to` defaults to the log's current head.
+func diffHandler(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    logId := eventLog.LogID{}
+    err := logId.Parse(vars["logId"])
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    query := r.URL.Query()
+    if query.Get("from") == "" {
+        http.Error(w, "Must supply from in query.", http.StatusBadRequest)
+        return
+    }
+    from := event.EventID{}
+    err = from.Parse(query.Get("from"))
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    ctx := r.Context()
+    to := event.EventID{}
+    if query.Get("to") != "" {
+        err = to.Parse(query.Get("to"))
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+    } else {
+        to, err = getLogHead(ctx, logId)
+        if err != nil {
+            writeError(w, err)
+            return
+        }
+    }
+
+    ancestor, err := skips.CommonAncestor(ctx, from, to)
+    if err == source.ErrNotFound {
+        http.Error(w, "Unknown event.", http.StatusNotFound)
+        return
+    } else if err != nil {
+        writeError(w, err)
+        return
+    }
+
+    fromOnly, err := getEventHistory(ctx, from, ancestor)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+    toOnly, err := getEventHistory(ctx, to, ancestor)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+
+    writeJson(w, &diffResponse{
+        LogID: logId.String(),
+        From: from.String(),
+        To: to.String(),
+        CommonAncestor: ancestor.String(),
+        FromOnly: eventsJson(fromOnly),
+        ToOnly: eventsJson(toOnly),
+    })
+}
+
+// eventsJson converts a history, newest first, to JSON events oldest first.
+func eventsJson(events []*event.Event) []*eventJson {
+    l := len(events)
+    out := make([]*eventJson, l)
+    for i := 0; i < l; i++ {
+        e := events[l - 1 - i]
+        id := e.ID()
+
+        out[i] = &eventJson{
+            EventID: id.String(),
+            Type: e.Type,
+            Data: base64.StdEncoding.EncodeToString(e.Data),
+        }
+    }
+    return out
+}
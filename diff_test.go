@@ -0,0 +1,134 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "testing"
+
+    "github.com/tobyjsullivan/event-log-reader/cache"
+    "github.com/tobyjsullivan/event-log-reader/chain"
+    "github.com/tobyjsullivan/event-log-reader/source"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// testSource serves events held in memory.
+type testSource map[event.EventID]*event.Event
+
+func (s testSource) GetEvent(ctx context.Context, id event.EventID) (*event.Event, error) {
+    e, ok := s[id]
+    if !ok {
+        return nil, source.ErrNotFound
+    }
+    return e, nil
+}
+
+// useEvents swaps the event store for one holding only the given events, with empty
+// indexes over it. Call the returned func to restore them.
+func useEvents(events ...*event.Event) func() {
+    return useSource(newTestSource(events...))
+}
+
+func newTestSource(events ...*event.Event) testSource {
+    src := make(testSource, len(events))
+    for _, e := range events {
+        src[e.ID()] = e
+    }
+    return src
+}
+
+// useSource is useEvents for a source that may not hold the events its IDs name.
+func useSource(src testSource) func() {
+    prevStore, prevLinks, prevSkips, prevSegments, prevLogIndex := eventStore, links, skips, segments, logIndex
+    eventStore = cache.NewStore(src, &cache.StoreOptions{})
+    links = chain.NewMemoryLinks(1000)
+    skips = chain.NewSkipIndex(1000, 0, previousEvents)
+    segments = chain.NewSegments(1000)
+    logIndex = chain.NewLogIndex(1000)
+    return func() {
+        eventStore, links, skips, segments, logIndex = prevStore, prevLinks, prevSkips, prevSegments, prevLogIndex
+    }
+}
+
+// testEvents appends n events of the given type onto prev and returns them oldest
+// first.
+func testEvents(prev event.EventID, eventType string, n int) []*event.Event {
+    out := make([]*event.Event, n)
+    for i := range out {
+        out[i] = &event.Event{
+            PreviousEvent: prev,
+            Type: eventType,
+            Data: event.EventData{byte(i)},
+        }
+        prev = out[i].ID()
+    }
+    return out
+}
+
+func TestDiffHandler(t *testing.T) {
+    // A trunk of five events with a three event fork from its second.
+    trunk := testEvents(event.EventID{}, "Trunk", 5)
+    fork := testEvents(trunk[1].ID(), "Fork", 3)
+    restoreEvents := useEvents(append(trunk, fork...)...)
+    defer restoreEvents()
+
+    head := trunk[4].ID()
+    restoreHeads := useMemoryHeads(t, map[string]string{
+        testLogA: head.String(),
+    })
+    defer restoreHeads()
+
+    forkHead := fork[2].ID()
+    var got diffResponse
+    if status := serve(t, "GET", "/logs/" + testLogA + "/diff?from=" + forkHead.String(), "", &got); status != http.StatusOK {
+        t.Fatalf("got status %d", status)
+    }
+
+    ancestor := trunk[1].ID()
+    forkStart := fork[0].ID()
+    if got.From != forkHead.String() || got.To != head.String() {
+        t.Errorf("got from %s to %s, want to default to the head", got.From, got.To)
+    }
+    if got.CommonAncestor != ancestor.String() {
+        t.Errorf("got common ancestor %s, want the fork point", got.CommonAncestor)
+    }
+    if len(got.FromOnly) != 3 || got.FromOnly[0].Type != "Fork" || got.FromOnly[0].EventID != forkStart.String() {
+        t.Errorf("got %d events only in from, want the fork oldest first", len(got.FromOnly))
+    }
+    if len(got.ToOnly) != 3 || got.ToOnly[2].EventID != head.String() {
+        t.Errorf("got %d events only in to, want the rest of the trunk oldest first", len(got.ToOnly))
+    }
+
+    // Diffing an event against itself finds nothing on either side.
+    if status := serve(t, "GET", "/logs/" + testLogA + "/diff?from=" + head.String() + "&to=" + head.String(), "", &got); status != http.StatusOK {
+        t.Fatalf("same event: got status %d", status)
+    }
+    if got.CommonAncestor != head.String() || len(got.FromOnly) != 0 || len(got.ToOnly) != 0 {
+        t.Errorf("same event: got common ancestor %s with %d and %d events", got.CommonAncestor, len(got.FromOnly), len(got.ToOnly))
+    }
+}
+
+func TestDiffHandlerErrors(t *testing.T) {
+    trunk := testEvents(event.EventID{}, "Trunk", 3)
+    restoreEvents := useEvents(trunk...)
+    defer restoreEvents()
+    restoreHeads := useMemoryHeads(t, nil)
+    defer restoreHeads()
+
+    known := trunk[2].ID()
+    cases := []struct {
+        name string
+        query string
+        status int
+    }{
+        {"no from", "", http.StatusBadRequest},
+        {"invalid from", "?from=nope", http.StatusBadRequest},
+        {"invalid to", "?from=" + known.String() + "&to=nope", http.StatusBadRequest},
+        {"unknown from", "?from=" + testHeadA + "&to=" + known.String(), http.StatusNotFound},
+        {"unknown to", "?from=" + known.String() + "&to=" + testHeadB, http.StatusNotFound},
+    }
+    for _, tc := range cases {
+        if status := serve(t, "GET", "/logs/" + testLogA + "/diff" + tc.query, "", nil); status != tc.status {
+            t.Errorf("%s: got status %d, want %d", tc.name, status, tc.status)
+        }
+    }
+}
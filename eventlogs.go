@@ -0,0 +1,73 @@
+package main
+
+import (
+    "net/http"
+    "sort"
+
+    "github.com/gorilla/mux"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+type eventLogsResponse struct {
+    EventID string `json:"eventId"`
+    Logs []*eventLogJson `json:"logs"`
+}
+
+type eventLogJson struct {
+    LogID string `json:"logId"`
+    Position uint64 `json:"position"`
+}
+
+// eventLogsHandler lists the logs known to include the event. Candidates from the
+// index are checked against each log's current head before being reported.
+func eventLogsHandler(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    eventId := event.EventID{}
+    err := eventId.Parse(vars["eventId"])
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    ctx := r.Context()
+    candidates := logIndex.Logs(eventId)
+    out := make([]*eventLogJson, 0, len(candidates))
+
+    // An event's position is the same in every log that includes it.
+    var position uint64
+    if len(candidates) > 0 {
+        position, err = skips.Height(ctx, eventId)
+        if err != nil {
+            writeError(w, err)
+            return
+        }
+    }
+
+    for _, logId := range candidates {
+        head, err := getLogHead(ctx, logId)
+        if err != nil {
+            writeError(w, err)
+            return
+        }
+
+        ok, err := skips.IsAncestor(ctx, eventId, head)
+        if err != nil {
+            writeError(w, err)
+            return
+        }
+        if !ok {
+            continue
+        }
+
+        out = append(out, &eventLogJson{
+            LogID: logId.String(),
+            Position: position,
+        })
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].LogID < out[j].LogID })
+
+    writeJson(w, &eventLogsResponse{
+        EventID: eventId.String(),
+        Logs: out,
+    })
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+    "database/sql"
+    "fmt"
+    "os"
+
+    _ "github.com/lib/pq"
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+)
+
+const (
+    DEFAULT_HEAD_STORE = "postgres"
+    DEFAULT_HEADS_FILE = "data/heads.json"
+    DEFAULT_SQLITE_PATH = "data/logs.db"
+    SQLITE_DRIVER = "sqlite3"
+)
+
+// headStores maps the names accepted in HEAD_STORE to their constructors.
+var headStores = map[string]func() (eventLog.HeadStore, error){
+    "postgres": newPostgresHeads,
+    "file": newFileHeads,
+    "sqlite": newSQLiteHeads,
+}
+
+func buildHeadStore(name string) (eventLog.HeadStore, error) {
+    if name == "" {
+        name = DEFAULT_HEAD_STORE
+    }
+
+    build, ok := headStores[name]
+    if !ok {
+        return nil, fmt.Errorf("unknown head store %q", name)
+    }
+    return build()
+}
+
+func newPostgresHeads() (eventLog.HeadStore, error) {
+    pgHostname := os.Getenv("PG_HOSTNAME")
+    pgUsername := os.Getenv("PG_USERNAME")
+    pgPassword := os.Getenv("PG_PASSWORD")
+    pgDatabase := os.Getenv("PG_DATABASE")
+
+    dbConnOpts := fmt.Sprintf("host='%s' user='%s' dbname='%s' password='%s' sslmode=disable",
+        pgHostname, pgUsername, pgDatabase, pgPassword)
+
+    logger.Println("Connecting to DB...")
+    db, err := sql.Open("postgres", dbConnOpts)
+    if err != nil {
+        return nil, err
+    }
+    return eventLog.NewPostgresHeads(db, os.Getenv("PG_LOGS_UPDATED_COLUMN")), nil
+}
+
+func newFileHeads() (eventLog.HeadStore, error) {
+    path := os.Getenv("HEADS_FILE")
+    if path == "" {
+        path = DEFAULT_HEADS_FILE
+    }
+    return eventLog.NewFileHeads(path), nil
+}
+
+func newSQLiteHeads() (eventLog.HeadStore, error) {
+    if !hasDriver(SQLITE_DRIVER) {
+        return nil, fmt.Errorf("this binary was built without SQLite support; rebuild with -tags sqlite")
+    }
+
+    path := os.Getenv("SQLITE_PATH")
+    if path == "" {
+        path = DEFAULT_SQLITE_PATH
+    }
+
+    db, err := sql.Open(SQLITE_DRIVER, path)
+    if err != nil {
+        return nil, err
+    }
+    return eventLog.NewSQLiteHeads(db)
+}
+
+func hasDriver(name string) bool {
+    for _, d := range sql.Drivers() {
+        if d == name {
+            return true
+        }
+    }
+    return false
+}
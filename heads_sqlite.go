@@ -0,0 +1,8 @@
+//go:build sqlite
+// +build sqlite
+
+package main
+
+// The SQLite driver uses cgo, so it is only linked in when building with
+// `-tags sqlite`. It isn't vendored; fetch it into GOPATH first.
+import _ "github.com/mattn/go-sqlite3"
@@ -0,0 +1,92 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// useMemoryHeads swaps the global head store for an in-memory one holding the given
+// heads, keyed by log ID string. Call the returned func to restore it.
+func useMemoryHeads(t *testing.T, logs map[string]string) func() {
+    store := eventLog.NewMemoryHeads()
+    for logIdParam, headParam := range logs {
+        var logId eventLog.LogID
+        var head event.EventID
+        if err := logId.Parse(logIdParam); err != nil {
+            t.Fatal(err)
+        }
+        if err := head.Parse(headParam); err != nil {
+            t.Fatal(err)
+        }
+        store.Set(logId, head)
+    }
+
+    prev := heads
+    heads = store
+    return func() { heads = prev }
+}
+
+// serve sends a request through the API's routes and decodes the JSON response's
+// data into out, unless the status isn't 200.
+func serve(t *testing.T, method, target, body string, out interface{}) int {
+    req := httptest.NewRequest(method, target, strings.NewReader(body))
+    rec := httptest.NewRecorder()
+    buildRoutes().ServeHTTP(rec, req)
+
+    if rec.Code == http.StatusOK && out != nil {
+        resp := &jsonResponse{
+            Data: out,
+        }
+        if err := json.Unmarshal(rec.Body.Bytes(), resp); err != nil {
+            t.Fatalf("%s %s: %s", method, target, err.Error())
+        }
+    }
+    return rec.Code
+}
+
+const (
+    testLogA = "5f0c3b8e-1111-4222-8333-444455556666"
+    testLogB = "6f0c3b8e-1111-4222-8333-444455556666"
+    testLogC = "7f0c3b8e-1111-4222-8333-444455556666"
+    testHeadA = "a332561f47b61da9c0fd4c085330053f34249e6be316a486d3c8ca29318f1561"
+    testHeadB = "063249f439b6cf71e6fcb4d49f274fca27443256c62d1146e20a41d936e06b34"
+    zeroHead = "0000000000000000000000000000000000000000000000000000000000000000"
+)
+
+func TestReadLogHandler(t *testing.T) {
+    restore := useMemoryHeads(t, map[string]string{
+        testLogA: testHeadA,
+    })
+    defer restore()
+
+    cases := []struct {
+        name string
+        logId string
+        status int
+        head string
+    }{
+        {"known log", testLogA, http.StatusOK, testHeadA},
+        {"unknown log is empty", testLogB, http.StatusOK, zeroHead},
+        {"invalid log ID", "nope", http.StatusBadRequest, ""},
+    }
+    for _, tc := range cases {
+        var got readLogResponse
+        status := serve(t, "GET", "/logs/" + tc.logId, "", &got)
+        if status != tc.status {
+            t.Errorf("%s: got status %d, want %d", tc.name, status, tc.status)
+            continue
+        }
+        if status != http.StatusOK {
+            continue
+        }
+        if got.LogID != tc.logId || got.Head != tc.head {
+            t.Errorf("%s: got log %s head %s, want head %s", tc.name, got.LogID, got.Head, tc.head)
+        }
+    }
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+
+    "github.com/go-redis/redis"
+    "github.com/tobyjsullivan/event-log-reader/breaker"
+    "github.com/tobyjsullivan/event-log-reader/cache"
+    "github.com/tobyjsullivan/event-log-reader/chain"
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+const (
+    DEFAULT_LINK_INDEX_MAX_KEYS = 1000000
+    DEFAULT_SKIP_INDEX_MAX_KEYS = 1000000
+    DEFAULT_SKIP_INDEX_MAX_WALK = 100000
+    DEFAULT_SEGMENT_CACHE_MAX_IDS = 1000000
+    LOG_INDEX_MAX_KEYS = 1000000
+    // HISTORY_BATCH_SIZE is the most events resolved from the link index and loaded
+    // together in one step of a history walk.
+    HISTORY_BATCH_SIZE = 100
+)
+
+var errAfterNotInHistory = errors.New("The after event is not in the log's history.")
+
+// buildLinkIndex builds the index named by LINK_INDEX. The redis index shares the
+// client and breaker of the Redis cache tier when there is one.
+func buildLinkIndex(name string, tiers []cache.Tier) (chain.LinkIndex, error) {
+    maxKeys, err := envInt64("LINK_INDEX_MAX_KEYS", DEFAULT_LINK_INDEX_MAX_KEYS)
+    if err != nil {
+        return nil, err
+    }
+
+    switch name {
+    case "", "memory":
+        return chain.NewMemoryLinks(int(maxKeys)), nil
+    case "redis":
+        ttl, err := envDuration("LINK_INDEX_TTL", chain.DEFAULT_REDIS_LINKS_TTL)
+        if err != nil {
+            return nil, err
+        }
+
+        client, b, err := linkIndexRedis(tiers)
+        if err != nil {
+            return nil, err
+        }
+        return chain.NewRedisLinks(client, b, chain.NewMemoryLinks(int(maxKeys)), &chain.RedisLinksOptions{
+            KeyPrefix: os.Getenv("REDIS_KEY_PREFIX"),
+            TTL: ttl,
+        }, logger), nil
+    case "none":
+        return chain.NoLinks{}, nil
+    }
+
+    return nil, fmt.Errorf("unknown link index %q", name)
+}
+
+func linkIndexRedis(tiers []cache.Tier) (redis.UniversalClient, *breaker.Breaker, error) {
+    for _, t := range tiers {
+        if rt, ok := t.(*cache.RedisTier); ok {
+            return rt.Client(), rt.Breaker(), nil
+        }
+    }
+
+    // Without a Redis tier there's no health probe, so the breaker lets a trial call
+    // through after a cooldown instead.
+    client, err := newRedisClient()
+    if err != nil {
+        return nil, nil, err
+    }
+    threshold, err := envInt64("REDIS_FAILURE_THRESHOLD", DEFAULT_REDIS_FAILURE_THRESHOLD)
+    if err != nil {
+        return nil, nil, err
+    }
+    cooldown, err := envDuration("REDIS_PROBE_INTERVAL", cache.DEFAULT_PROBE_INTERVAL)
+    if err != nil {
+        return nil, nil, err
+    }
+    return client, breaker.New(int(threshold), cooldown), nil
+}
+
+// getLogHistory returns the log's events from head back to, but excluding, after,
+// newest first. It keeps the log's resolved chain in a Segment so that once the head
+// advances, only the new events need to be walked.
+func getLogHistory(ctx context.Context, logId eventLog.LogID, head, after event.EventID) ([]*event.Event, error) {
+    zero := event.EventID{}
+
+    seg, ok := segments.Get(logId)
+    if !ok && after != zero {
+        // Don't walk the whole log to build a segment when the caller only wants the
+        // tail of it.
+        return getHistoryAfter(ctx, logId, head, after)
+    }
+
+    seg, walked, err := resolveSegment(ctx, logId, head, seg)
+    if err != nil {
+        return []*event.Event{}, err
+    }
+
+    ids, ok := seg.Between(after, head)
+    if !ok {
+        return []*event.Event{}, errAfterNotInHistory
+    }
+
+    // Reuse the events loaded while extending the segment, and batch load the rest.
+    events := make([]*event.Event, len(ids))
+    missing := make([]event.EventID, 0)
+    for i, id := range ids {
+        if e, ok := walked[id]; ok {
+            events[len(ids) - 1 - i] = e
+        } else {
+            missing = append(missing, id)
+        }
+    }
+
+    loaded := make(map[event.EventID]*event.Event, len(missing))
+    for i := 0; i < len(missing); i += HISTORY_BATCH_SIZE {
+        end := i + HISTORY_BATCH_SIZE
+        if end > len(missing) {
+            end = len(missing)
+        }
+
+        batch, err := eventStore.GetEvents(ctx, missing[i:end])
+        if err != nil {
+            return []*event.Event{}, err
+        }
+        for j, e := range batch {
+            loaded[missing[i + j]] = e
+        }
+    }
+    for i, id := range ids {
+        if e, ok := loaded[id]; ok {
+            events[len(ids) - 1 - i] = e
+        }
+    }
+
+    return events, nil
+}
+
+// resolveSegment returns a segment of the log that includes head, extending or
+// replacing seg if it is older. seg may be nil. It also returns any events it had to
+// load, by ID.
+func resolveSegment(ctx context.Context, logId eventLog.LogID, head event.EventID, seg *chain.Segment) (*chain.Segment, map[event.EventID]*event.Event, error) {
+    zero := event.EventID{}
+    if head == zero {
+        return chain.NewSegment(zero, nil), nil, nil
+    }
+
+    if seg != nil {
+        if _, ok := seg.Position(head); ok {
+            return seg, nil, nil
+        }
+
+        from := seg.Head()
+        ids, events, end, err := walk(ctx, head, from)
+        if err != nil {
+            return nil, nil, err
+        }
+        walked := eventsById(ids, events)
+
+        if end == from && seg.Extend(from, head, ids) {
+            segments.Put(logId, seg)
+            logIndex.Add(logId, ids)
+            return seg, walked, nil
+        }
+        if _, ok := seg.Position(head); ok {
+            // Another request extended it first.
+            return seg, walked, nil
+        }
+        if end == zero {
+            // The log doesn't descend from the old head, so start over with the chain
+            // that was just walked.
+            seg = chain.NewSegment(head, ids)
+            segments.Put(logId, seg)
+            logIndex.Add(logId, ids)
+            return seg, walked, nil
+        }
+    }
+
+    ids, events, _, err := walk(ctx, head, zero)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    seg = chain.NewSegment(head, ids)
+    segments.Put(logId, seg)
+    logIndex.Add(logId, ids)
+    return seg, eventsById(ids, events), nil
+}
+
+func eventsById(ids []event.EventID, events []*event.Event) map[event.EventID]*event.Event {
+    out := make(map[event.EventID]*event.Event, len(ids))
+    for i, id := range ids {
+        out[id] = events[i]
+    }
+    return out
+}
+
+// getHistoryAfter walks from head back to after, failing if after isn't in the
+// history. Only the events walked are added to the log index.
+func getHistoryAfter(ctx context.Context, logId eventLog.LogID, head, after event.EventID) ([]*event.Event, error) {
+    if skips.Known(after) && skips.Known(head) {
+        ok, err := skips.IsAncestor(ctx, after, head)
+        if err != nil {
+            return []*event.Event{}, err
+        }
+        if !ok {
+            return []*event.Event{}, errAfterNotInHistory
+        }
+    }
+
+    // The walk only stops short of the genesis event if it met the after event.
+    ids, events, end, err := walk(ctx, head, after)
+    if err != nil {
+        return []*event.Event{}, err
+    }
+    if end != after {
+        return []*event.Event{}, errAfterNotInHistory
+    }
+
+    logIndex.Add(logId, ids)
+    return events, nil
+}
+
+// getEventHistory returns the events from head back to, but excluding, last.
+func getEventHistory(ctx context.Context, head event.EventID, last event.EventID) ([]*event.Event, error) {
+    _, events, _, err := walk(ctx, head, last)
+    return events, err
+}
+
+// walk follows the chain from head back to last or the genesis event, returning the
+// IDs and events it passed, newest first, and the ID it ended at.
+func walk(ctx context.Context, head event.EventID, last event.EventID) ([]event.EventID, []*event.Event, event.EventID, error) {
+    out := make([]*event.Event, 0)
+    ids := make([]event.EventID, 0)
+    end, err := walkChain(ctx, head, last, func(id event.EventID, e *event.Event) bool {
+        ids = append(ids, id)
+        out = append(out, e)
+        return true
+    })
+    if err != nil {
+        return nil, []*event.Event{}, event.EventID{}, err
+    }
+
+    // Only indexed if the walk ended at the genesis event or an already indexed one.
+    skips.Extend(ids, end)
+
+    return ids, out, end, nil
+}
+
+// walkChain follows the chain from head back to, but excluding, last or the genesis
+// event, calling step with each event, newest first, until it returns false. The link
+// index resolves runs of IDs up front so each run's events can be loaded in a batch;
+// where links are unknown it falls back to one event at a time.
+//
+// It returns where the walk stopped: last or the zero ID, or the event step returned
+// false for. If the chain leads to an event that can't be read, it returns that
+// event's ID along with the error.
+func walkChain(ctx context.Context, head event.EventID, last event.EventID, step func(id event.EventID, e *event.Event) bool) (event.EventID, error) {
+    zero := event.EventID{}
+    for head != last && head != zero {
+        if err := ctx.Err(); err != nil {
+            return head, err
+        }
+
+        batch := links.Walk(ctx, head, last, HISTORY_BATCH_SIZE)
+        events, err := eventStore.GetEvents(ctx, batch)
+        if err != nil {
+            // Read the batch in order to find the event that failed.
+            events, err = getEventsInOrder(ctx, batch)
+        }
+
+        if len(events) > 0 {
+            // Every link but the last was just read from the index.
+            end := len(events) - 1
+            links.Add(batch[end], events[end].PreviousEvent)
+        }
+
+        for i, e := range events {
+            if !step(batch[i], e) {
+                return batch[i], nil
+            }
+            head = e.PreviousEvent
+
+            // Trust the events over the index if they disagree.
+            if i + 1 < len(batch) && batch[i + 1] != head {
+                break
+            }
+        }
+
+        // The failed event only matters if the chain actually leads to it.
+        if err != nil && head == batch[len(events)] {
+            return head, err
+        }
+    }
+    return head, nil
+}
+
+// getEventsInOrder reads the events one at a time, returning those read before the
+// first error.
+func getEventsInOrder(ctx context.Context, ids []event.EventID) ([]*event.Event, error) {
+    out := make([]*event.Event, 0, len(ids))
+    for _, id := range ids {
+        e, err := eventStore.GetEvent(ctx, id)
+        if err != nil {
+            return out, err
+        }
+        out = append(out, e)
+    }
+    return out, nil
+}
+
+func getEvent(ctx context.Context, id event.EventID) (*event.Event, error) {
+    e, err := eventStore.GetEvent(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+
+    links.Add(id, e.PreviousEvent)
+    return e, nil
+}
+
+// previousEvents resolves links for the skip index, a run at a time where they are
+// indexed.
+func previousEvents(ctx context.Context, id event.EventID) ([]event.EventID, error) {
+    if ids := links.Walk(ctx, id, event.EventID{}, HISTORY_BATCH_SIZE + 1); len(ids) > 1 {
+        return ids[1:], nil
+    }
+
+    e, err := getEvent(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    return []event.EventID{e.PreviousEvent}, nil
+}
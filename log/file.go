@@ -0,0 +1,125 @@
+package log
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "sync"
+    "time"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// FileHeads reads heads from a JSON file mapping log IDs to hex head event IDs, for
+// local development:
+//
+//     {"5f0c3b8e-...": "9a1d..."}
+//
+// The file is reloaded whenever it changes, so fixtures can be edited while the
+// service runs. A missing file is an empty store.
+type FileHeads struct {
+    path string
+
+    mu sync.Mutex
+    modTime time.Time
+    size int64
+    heads map[LogID]event.EventID
+}
+
+func NewFileHeads(path string) *FileHeads {
+    return &FileHeads{
+        path: path,
+    }
+}
+
+func (s *FileHeads) Head(ctx context.Context, id LogID) (event.EventID, error) {
+    heads, err := s.load()
+    if err != nil {
+        return event.EventID{}, err
+    }
+    return heads[id], nil
+}
+
+func (s *FileHeads) Heads(ctx context.Context) (map[LogID]event.EventID, error) {
+    heads, err := s.load()
+    if err != nil {
+        return nil, err
+    }
+
+    out := make(map[LogID]event.EventID, len(heads))
+    for id, head := range heads {
+        out[id] = head
+    }
+    return out, nil
+}
+
+func (s *FileHeads) HeadsOf(ctx context.Context, ids []LogID) (map[LogID]event.EventID, error) {
+    heads, err := s.load()
+    if err != nil {
+        return nil, err
+    }
+    return headsOf(heads, ids), nil
+}
+
+func (s *FileHeads) List(ctx context.Context, opts *ListOptions) ([]*LogHead, error) {
+    if !opts.UpdatedSince.IsZero() {
+        return nil, ErrFilterUnsupported
+    }
+
+    heads, err := s.load()
+    if err != nil {
+        return nil, err
+    }
+    return listHeads(heads, opts), nil
+}
+
+// load returns the parsed file, re-reading it if it has changed. The returned map
+// must not be modified.
+func (s *FileHeads) load() (map[LogID]event.EventID, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    info, err := os.Stat(s.path)
+    if os.IsNotExist(err) {
+        s.heads = nil
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    if s.heads != nil && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+        return s.heads, nil
+    }
+
+    b, err := ioutil.ReadFile(s.path)
+    if err != nil {
+        return nil, err
+    }
+    var raw map[string]string
+    err = json.Unmarshal(b, &raw)
+    if err != nil {
+        return nil, fmt.Errorf("invalid heads file %s: %s", s.path, err.Error())
+    }
+
+    heads := make(map[LogID]event.EventID, len(raw))
+    for k, v := range raw {
+        var id LogID
+        err = id.Parse(k)
+        if err != nil {
+            return nil, fmt.Errorf("invalid log ID %q in %s: %s", k, s.path, err.Error())
+        }
+        var head event.EventID
+        err = head.Parse(v)
+        if err != nil {
+            return nil, fmt.Errorf("invalid head %q in %s: %s", v, s.path, err.Error())
+        }
+        heads[id] = head
+    }
+
+    s.heads = heads
+    s.modTime = info.ModTime()
+    s.size = info.Size()
+    return heads, nil
+}
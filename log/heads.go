@@ -0,0 +1,79 @@
+package log
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "sort"
+    "time"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// ErrFilterUnsupported means the store can't apply a requested ListOptions filter.
+var ErrFilterUnsupported = errors.New("the log head store doesn't support this filter")
+
+// HeadStore looks up the current head event of logs.
+type HeadStore interface {
+    // Head returns the log's head event. A log with no record is treated as an empty
+    // log and has the zero ID.
+    Head(ctx context.Context, id LogID) (event.EventID, error)
+    // Heads returns the head of every log in the store.
+    Heads(ctx context.Context) (map[LogID]event.EventID, error)
+    // HeadsOf looks up several logs at once. Logs with no record are left out.
+    HeadsOf(ctx context.Context, ids []LogID) (map[LogID]event.EventID, error)
+    // List returns a page of logs ordered by ID.
+    List(ctx context.Context, opts *ListOptions) ([]*LogHead, error)
+}
+
+type ListOptions struct {
+    // After is the last log of the previous page, or nil for the first page.
+    After *LogID
+    Limit int
+    // UpdatedSince, unless zero, only lists logs updated at or after this time.
+    // Stores that don't track updates return ErrFilterUnsupported.
+    UpdatedSince time.Time
+}
+
+type LogHead struct {
+    ID LogID
+    Head event.EventID
+    // Updated is zero if the store doesn't track updates.
+    Updated time.Time
+}
+
+// headsOf picks the given logs out of heads held in a map.
+func headsOf(heads map[LogID]event.EventID, ids []LogID) map[LogID]event.EventID {
+    out := make(map[LogID]event.EventID, len(ids))
+    for _, id := range ids {
+        if head, ok := heads[id]; ok {
+            out[id] = head
+        }
+    }
+    return out
+}
+
+// listHeads pages through heads held in a map, for the stores that keep them in
+// memory.
+func listHeads(heads map[LogID]event.EventID, opts *ListOptions) []*LogHead {
+    ids := make([]LogID, 0, len(heads))
+    for id := range heads {
+        if opts.After != nil && bytes.Compare(id[:], opts.After[:]) <= 0 {
+            continue
+        }
+        ids = append(ids, id)
+    }
+    sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+    if len(ids) > opts.Limit {
+        ids = ids[:opts.Limit]
+    }
+
+    out := make([]*LogHead, len(ids))
+    for i, id := range ids {
+        out[i] = &LogHead{
+            ID: id,
+            Head: heads[id],
+        }
+    }
+    return out
+}
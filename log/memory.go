@@ -0,0 +1,79 @@
+package log
+
+import (
+    "context"
+    "sync"
+    "time"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// MemoryHeads is a HeadStore held in memory, for tests and for stubbing out the
+// database.
+type MemoryHeads struct {
+    mu sync.RWMutex
+    heads map[LogID]event.EventID
+    updated map[LogID]time.Time
+}
+
+func NewMemoryHeads() *MemoryHeads {
+    return &MemoryHeads{
+        heads: make(map[LogID]event.EventID),
+        updated: make(map[LogID]time.Time),
+    }
+}
+
+// Set records the log's head.
+func (s *MemoryHeads) Set(id LogID, head event.EventID) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.heads[id] = head
+    s.updated[id] = time.Now()
+}
+
+func (s *MemoryHeads) Head(ctx context.Context, id LogID) (event.EventID, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    return s.heads[id], nil
+}
+
+func (s *MemoryHeads) Heads(ctx context.Context) (map[LogID]event.EventID, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    out := make(map[LogID]event.EventID, len(s.heads))
+    for id, head := range s.heads {
+        out[id] = head
+    }
+    return out, nil
+}
+
+func (s *MemoryHeads) HeadsOf(ctx context.Context, ids []LogID) (map[LogID]event.EventID, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    return headsOf(s.heads, ids), nil
+}
+
+func (s *MemoryHeads) List(ctx context.Context, opts *ListOptions) ([]*LogHead, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    heads := s.heads
+    if !opts.UpdatedSince.IsZero() {
+        heads = make(map[LogID]event.EventID)
+        for id, head := range s.heads {
+            if !s.updated[id].Before(opts.UpdatedSince) {
+                heads[id] = head
+            }
+        }
+    }
+
+    out := listHeads(heads, opts)
+    for _, l := range out {
+        l.Updated = s.updated[l.ID]
+    }
+    return out, nil
+}
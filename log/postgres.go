@@ -0,0 +1,137 @@
+package log
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+
+    "github.com/lib/pq"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// PostgresHeads reads heads from the logs table maintained by the event-log service.
+type PostgresHeads struct {
+    db *sql.DB
+    updatedColumn string
+}
+
+// NewPostgresHeads reads the logs table. updatedColumn names a timestamp column
+// holding when each log's head last changed, if the table has one; without it,
+// listing can't filter by update time.
+func NewPostgresHeads(db *sql.DB, updatedColumn string) *PostgresHeads {
+    return &PostgresHeads{
+        db: db,
+        updatedColumn: updatedColumn,
+    }
+}
+
+func (s *PostgresHeads) Head(ctx context.Context, id LogID) (event.EventID, error) {
+    var head []byte
+    err := s.db.QueryRowContext(ctx, `SELECT head FROM logs WHERE ext_lookup_key=$1`, id[:]).Scan(&head)
+    if err == sql.ErrNoRows {
+        return event.EventID{}, nil
+    }
+    if err != nil {
+        return event.EventID{}, err
+    }
+
+    var out event.EventID
+    copy(out[:], head)
+    return out, nil
+}
+
+func (s *PostgresHeads) Heads(ctx context.Context) (map[LogID]event.EventID, error) {
+    rows, err := s.db.QueryContext(ctx, `SELECT ext_lookup_key, head FROM logs`)
+    if err != nil {
+        return nil, err
+    }
+    return scanHeads(rows)
+}
+
+// HeadsOf looks up every log in a single query.
+func (s *PostgresHeads) HeadsOf(ctx context.Context, ids []LogID) (map[LogID]event.EventID, error) {
+    keys := make(pq.ByteaArray, len(ids))
+    for i := range ids {
+        keys[i] = ids[i][:]
+    }
+
+    rows, err := s.db.QueryContext(ctx, `SELECT ext_lookup_key, head FROM logs WHERE ext_lookup_key = ANY($1)`, keys)
+    if err != nil {
+        return nil, err
+    }
+    return scanHeads(rows)
+}
+
+func (s *PostgresHeads) List(ctx context.Context, opts *ListOptions) ([]*LogHead, error) {
+    if !opts.UpdatedSince.IsZero() && s.updatedColumn == "" {
+        return nil, ErrFilterUnsupported
+    }
+
+    after := []byte{}
+    if opts.After != nil {
+        after = opts.After[:]
+    }
+
+    columns := `ext_lookup_key, head`
+    where := `ext_lookup_key > $1`
+    args := []interface{}{after}
+    if s.updatedColumn != "" {
+        updated := pq.QuoteIdentifier(s.updatedColumn)
+        columns += `, ` + updated
+        if !opts.UpdatedSince.IsZero() {
+            args = append(args, opts.UpdatedSince)
+            where += fmt.Sprintf(` AND %s >= $%d`, updated, len(args))
+        }
+    }
+    args = append(args, opts.Limit)
+    query := fmt.Sprintf(`SELECT %s FROM logs WHERE %s ORDER BY ext_lookup_key LIMIT $%d`, columns, where, len(args))
+
+    rows, err := s.db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    out := make([]*LogHead, 0)
+    for rows.Next() {
+        var key, head []byte
+        var updated pq.NullTime
+        dest := []interface{}{&key, &head}
+        if s.updatedColumn != "" {
+            dest = append(dest, &updated)
+        }
+        err := rows.Scan(dest...)
+        if err != nil {
+            return nil, err
+        }
+
+        l := &LogHead{
+            Updated: updated.Time,
+        }
+        copy(l.ID[:], key)
+        copy(l.Head[:], head)
+        out = append(out, l)
+    }
+    return out, rows.Err()
+}
+
+// scanHeads reads (ext_lookup_key, head) rows.
+func scanHeads(rows *sql.Rows) (map[LogID]event.EventID, error) {
+    defer rows.Close()
+
+    out := make(map[LogID]event.EventID)
+    for rows.Next() {
+        var key, head []byte
+        err := rows.Scan(&key, &head)
+        if err != nil {
+            return nil, err
+        }
+
+        var logId LogID
+        var headId event.EventID
+        copy(logId[:], key)
+        copy(headId[:], head)
+        out[logId] = headId
+    }
+    return out, rows.Err()
+}
@@ -0,0 +1,58 @@
+package log
+
+import (
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// Proof shows that an event is part of the log ending at some head. Each event's ID
+// commits to its predecessor's, so hashing the events that follow Event, oldest
+// first, must arrive at the head. A log is a plain hash chain rather than a Merkle
+// tree, so a proof holds every event after Event in full and grows linearly with its
+// distance from the head.
+type Proof struct {
+    Event event.EventID
+    // Steps are the events after Event up to and including the head, oldest first.
+    // Their previous event IDs are implied by the chain.
+    Steps []ProofStep
+}
+
+type ProofStep struct {
+    Type string
+    Data event.EventData
+}
+
+// NewProof builds a proof from the events after id up to the head, oldest first.
+func NewProof(id event.EventID, events []*event.Event) *Proof {
+    steps := make([]ProofStep, len(events))
+    for i, e := range events {
+        steps[i] = ProofStep{
+            Type: e.Type,
+            Data: e.Data,
+        }
+    }
+
+    return &Proof{
+        Event: id,
+        Steps: steps,
+    }
+}
+
+// VerifyProof reports whether the proof shows that its event is part of the log
+// ending at head. It relies only on how event IDs are computed, not on whoever
+// produced the proof.
+func VerifyProof(head event.EventID, p *Proof) bool {
+    if p.Event == (event.EventID{}) {
+        return false
+    }
+
+    id := p.Event
+    for _, step := range p.Steps {
+        e := &event.Event{
+            PreviousEvent: id,
+            Type: step.Type,
+            Data: step.Data,
+        }
+        id = e.ID()
+    }
+    return id == head
+}
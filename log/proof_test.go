@@ -0,0 +1,67 @@
+package log
+
+import (
+    "testing"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// testLog builds a log of n events and returns them oldest first.
+func testLog(n int) []*event.Event {
+    out := make([]*event.Event, n)
+    prev := event.EventID{}
+    for i := range out {
+        out[i] = &event.Event{
+            PreviousEvent: prev,
+            Type: "TestEvent",
+            Data: event.EventData([]byte{byte(i)}),
+        }
+        prev = out[i].ID()
+    }
+    return out
+}
+
+func TestVerifyProof(t *testing.T) {
+    events := testLog(5)
+    head := events[4].ID()
+
+    for i, e := range events {
+        p := NewProof(e.ID(), events[i + 1:])
+        if len(p.Steps) != len(events) - i - 1 {
+            t.Errorf("event %d: got %d steps, want %d", i, len(p.Steps), len(events) - i - 1)
+        }
+        if !VerifyProof(head, p) {
+            t.Errorf("event %d: valid proof failed to verify", i)
+        }
+    }
+}
+
+func TestVerifyProofTampered(t *testing.T) {
+    events := testLog(5)
+    head := events[4].ID()
+    outsider := (&event.Event{Type: "Other"}).ID()
+
+    cases := []struct {
+        name string
+        tamper func(p *Proof)
+    }{
+        {"changed data", func(p *Proof) { p.Steps[1].Data = event.EventData([]byte("forged")) }},
+        {"changed type", func(p *Proof) { p.Steps[0].Type = "Forged" }},
+        {"dropped step", func(p *Proof) { p.Steps = p.Steps[1:] }},
+        {"reordered steps", func(p *Proof) { p.Steps[0], p.Steps[1] = p.Steps[1], p.Steps[0] }},
+        {"other event", func(p *Proof) { p.Event = outsider }},
+        {"zero event", func(p *Proof) { p.Event = event.EventID{} }},
+    }
+    for _, tc := range cases {
+        p := NewProof(events[1].ID(), events[2:])
+        tc.tamper(p)
+        if VerifyProof(head, p) {
+            t.Errorf("%s: tampered proof verified", tc.name)
+        }
+    }
+
+    p := NewProof(events[1].ID(), events[2:])
+    if VerifyProof(events[3].ID(), p) {
+        t.Errorf("proof verified against the wrong head")
+    }
+}
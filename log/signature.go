@@ -0,0 +1,33 @@
+package log
+
+import (
+    "crypto/ed25519"
+    "time"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// HEAD_SIGNATURE_CONTEXT prefixes signed head messages so the signatures can't be
+// mistaken for anything else signed with the same key.
+const HEAD_SIGNATURE_CONTEXT = "event-log-reader head v1"
+
+// HeadMessage is the message signed to attest that a log had the given head at the
+// given time: the context, log ID, head event ID and RFC 3339 timestamp, each on its
+// own line.
+func HeadMessage(id LogID, head event.EventID, at time.Time) []byte {
+    return []byte(HEAD_SIGNATURE_CONTEXT + "\n" +
+        id.String() + "\n" +
+        head.String() + "\n" +
+        at.UTC().Format(time.RFC3339Nano))
+}
+
+// SignHead signs HeadMessage with key.
+func SignHead(key ed25519.PrivateKey, id LogID, head event.EventID, at time.Time) []byte {
+    return ed25519.Sign(key, HeadMessage(id, head, at))
+}
+
+// VerifyHead reports whether sig is a valid signature by key of the log's head at the
+// given time.
+func VerifyHead(key ed25519.PublicKey, id LogID, head event.EventID, at time.Time, sig []byte) bool {
+    return ed25519.Verify(key, HeadMessage(id, head, at), sig)
+}
@@ -0,0 +1,127 @@
+package log
+
+import (
+    "context"
+    "database/sql"
+    "strings"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// sqliteMaxParams stays under the bound parameter limit of older SQLite builds (999).
+const sqliteMaxParams = 500
+
+// SQLiteHeads reads heads from a logs table in an embedded SQLite database, with the
+// same columns as the Postgres table. It works with any database/sql SQLite driver;
+// the binary only includes one when built with the sqlite tag.
+type SQLiteHeads struct {
+    db *sql.DB
+}
+
+// NewSQLiteHeads creates the logs table if it doesn't exist.
+func NewSQLiteHeads(db *sql.DB) (*SQLiteHeads, error) {
+    _, err := db.Exec(`CREATE TABLE IF NOT EXISTS logs (
+        ext_lookup_key BLOB PRIMARY KEY,
+        head BLOB NOT NULL
+    )`)
+    if err != nil {
+        return nil, err
+    }
+
+    return &SQLiteHeads{
+        db: db,
+    }, nil
+}
+
+// Set records the log's head.
+func (s *SQLiteHeads) Set(ctx context.Context, id LogID, head event.EventID) error {
+    _, err := s.db.ExecContext(ctx, `INSERT OR REPLACE INTO logs (ext_lookup_key, head) VALUES (?, ?)`, id[:], head[:])
+    return err
+}
+
+func (s *SQLiteHeads) Head(ctx context.Context, id LogID) (event.EventID, error) {
+    var head []byte
+    err := s.db.QueryRowContext(ctx, `SELECT head FROM logs WHERE ext_lookup_key = ?`, id[:]).Scan(&head)
+    if err == sql.ErrNoRows {
+        return event.EventID{}, nil
+    }
+    if err != nil {
+        return event.EventID{}, err
+    }
+
+    var out event.EventID
+    copy(out[:], head)
+    return out, nil
+}
+
+func (s *SQLiteHeads) Heads(ctx context.Context) (map[LogID]event.EventID, error) {
+    rows, err := s.db.QueryContext(ctx, `SELECT ext_lookup_key, head FROM logs`)
+    if err != nil {
+        return nil, err
+    }
+    return scanHeads(rows)
+}
+
+// HeadsOf looks up the logs in chunks, keeping under SQLite's limit on the number of
+// bound parameters.
+func (s *SQLiteHeads) HeadsOf(ctx context.Context, ids []LogID) (map[LogID]event.EventID, error) {
+    out := make(map[LogID]event.EventID, len(ids))
+    for i := 0; i < len(ids); i += sqliteMaxParams {
+        end := i + sqliteMaxParams
+        if end > len(ids) {
+            end = len(ids)
+        }
+
+        chunk := ids[i:end]
+        args := make([]interface{}, len(chunk))
+        for j := range chunk {
+            args[j] = chunk[j][:]
+        }
+        placeholders := strings.Repeat(",?", len(chunk))[1:]
+
+        rows, err := s.db.QueryContext(ctx, `SELECT ext_lookup_key, head FROM logs WHERE ext_lookup_key IN (` + placeholders + `)`, args...)
+        if err != nil {
+            return nil, err
+        }
+        heads, err := scanHeads(rows)
+        if err != nil {
+            return nil, err
+        }
+        for id, head := range heads {
+            out[id] = head
+        }
+    }
+    return out, nil
+}
+
+func (s *SQLiteHeads) List(ctx context.Context, opts *ListOptions) ([]*LogHead, error) {
+    if !opts.UpdatedSince.IsZero() {
+        return nil, ErrFilterUnsupported
+    }
+
+    after := []byte{}
+    if opts.After != nil {
+        after = opts.After[:]
+    }
+    rows, err := s.db.QueryContext(ctx, `SELECT ext_lookup_key, head FROM logs
+        WHERE ext_lookup_key > ? ORDER BY ext_lookup_key LIMIT ?`, after, opts.Limit)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    out := make([]*LogHead, 0)
+    for rows.Next() {
+        var key, head []byte
+        err := rows.Scan(&key, &head)
+        if err != nil {
+            return nil, err
+        }
+
+        l := &LogHead{}
+        copy(l.ID[:], key)
+        copy(l.Head[:], head)
+        out = append(out, l)
+    }
+    return out, rows.Err()
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "time"
+
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+)
+
+const (
+    DEFAULT_LIST_LOGS_LIMIT = 100
+    MAX_LIST_LOGS_LIMIT = 1000
+    MAX_BATCH_GET_LOGS = 1000
+    // MAX_BATCH_GET_BYTES fits MAX_BATCH_GET_LOGS quoted log IDs with room for
+    // whitespace.
+    MAX_BATCH_GET_BYTES = 64 << 10
+)
+
+type listLogsResponse struct {
+    Logs []*logJson `json:"logs"`
+    // NextCursor is passed as cursor to fetch the next page. It is empty on the last
+    // page.
+    NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type logJson struct {
+    LogID string `json:"logId"`
+    Head string `json:"head"`
+    // Updated is only set by head stores that track updates.
+    Updated *time.Time `json:"updated,omitempty"`
+}
+
+func listLogsHandler(w http.ResponseWriter, r *http.Request) {
+    query := r.URL.Query()
+    opts := &eventLog.ListOptions{
+        Limit: DEFAULT_LIST_LOGS_LIMIT,
+    }
+
+    if s := query.Get("limit"); s != "" {
+        limit, err := strconv.Atoi(s)
+        if err != nil || limit < 1 || limit > MAX_LIST_LOGS_LIMIT {
+            http.Error(w, "limit must be between 1 and " + strconv.Itoa(MAX_LIST_LOGS_LIMIT) + ".", http.StatusBadRequest)
+            return
+        }
+        opts.Limit = limit
+    }
+
+    // The cursor is the ID of the last log on the previous page.
+    if s := query.Get("cursor"); s != "" {
+        after := eventLog.LogID{}
+        err := after.Parse(s)
+        if err != nil {
+            http.Error(w, "Invalid cursor.", http.StatusBadRequest)
+            return
+        }
+        opts.After = &after
+    }
+
+    if s := query.Get("updatedSince"); s != "" {
+        since, err := time.Parse(time.RFC3339, s)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        opts.UpdatedSince = since
+    }
+
+    // Ask for one more log than fits on the page to tell whether there's a next page.
+    limit := opts.Limit
+    opts.Limit++
+    page, err := heads.List(r.Context(), opts)
+    if err == eventLog.ErrFilterUnsupported {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    } else if err != nil {
+        logger.Println("Error listing logs.", err.Error())
+        writeError(w, err)
+        return
+    }
+
+    more := len(page) > limit
+    if more {
+        page = page[:limit]
+    }
+
+    out := make([]*logJson, len(page))
+    for i, l := range page {
+        out[i] = &logJson{
+            LogID: l.ID.String(),
+            Head: l.Head.String(),
+        }
+        if !l.Updated.IsZero() {
+            updated := l.Updated.UTC()
+            out[i].Updated = &updated
+        }
+    }
+
+    resp := &listLogsResponse{
+        Logs: out,
+    }
+    if more {
+        resp.NextCursor = page[len(page) - 1].ID.String()
+    }
+    writeJson(w, resp)
+}
+
+type batchGetRequest struct {
+    LogIDs []string `json:"logIds"`
+}
+
+type batchGetResponse struct {
+    Logs []*readLogResponse `json:"logs"`
+}
+
+// batchGetLogsHandler returns the heads of up to MAX_BATCH_GET_LOGS logs with one
+// head store lookup, in the order requested. Unknown logs have the zero head, as in
+// readLogHandler, and heads are signed when responses are.
+func batchGetLogsHandler(w http.ResponseWriter, r *http.Request) {
+    var req batchGetRequest
+    err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_BATCH_GET_BYTES)).Decode(&req)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    if len(req.LogIDs) == 0 || len(req.LogIDs) > MAX_BATCH_GET_LOGS {
+        http.Error(w, "logIds must list between 1 and " + strconv.Itoa(MAX_BATCH_GET_LOGS) + " logs.", http.StatusBadRequest)
+        return
+    }
+
+    logIds := make([]eventLog.LogID, len(req.LogIDs))
+    for i, s := range req.LogIDs {
+        err := logIds[i].Parse(s)
+        if err != nil {
+            http.Error(w, "Invalid log ID " + strconv.Quote(s) + ".", http.StatusBadRequest)
+            return
+        }
+    }
+
+    found, err := heads.HeadsOf(r.Context(), logIds)
+    if err != nil {
+        logger.Println("Error looking up log heads.", err.Error())
+        writeError(w, err)
+        return
+    }
+
+    out := make([]*readLogResponse, len(logIds))
+    for i, logId := range logIds {
+        head := found[logId]
+        out[i] = &readLogResponse{
+            LogID: logId.String(),
+            Head: head.String(),
+        }
+        if signer != nil {
+            signer.sign(out[i], logId, head)
+        }
+    }
+
+    writeJson(w, &batchGetResponse{
+        Logs: out,
+    })
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+    "strings"
+    "testing"
+
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+)
+
+func TestListLogsHandler(t *testing.T) {
+    restore := useMemoryHeads(t, map[string]string{
+        testLogB: testHeadB,
+        testLogA: testHeadA,
+    })
+    defer restore()
+
+    var got listLogsResponse
+    if status := serve(t, "GET", "/logs", "", &got); status != http.StatusOK {
+        t.Fatalf("got status %d", status)
+    }
+    if len(got.Logs) != 2 {
+        t.Fatalf("got %d logs, want 2", len(got.Logs))
+    }
+    if got.Logs[0].LogID != testLogA || got.Logs[0].Head != testHeadA ||
+        got.Logs[1].LogID != testLogB || got.Logs[1].Head != testHeadB {
+        t.Errorf("got logs %s, %s, want them ordered by ID with their heads", got.Logs[0].LogID, got.Logs[1].LogID)
+    }
+}
+
+func TestListLogsPagination(t *testing.T) {
+    cases := []struct {
+        logs int
+        limit int
+        pages []int
+    }{
+        {5, 2, []int{2, 2, 1}},
+        // A full last page has no cursor to an empty page after it.
+        {4, 2, []int{2, 2}},
+        {3, 5, []int{3}},
+        {0, 2, []int{0}},
+    }
+
+    for _, tc := range cases {
+        logs := make(map[string]string, tc.logs)
+        for i := 0; i < tc.logs; i++ {
+            logs[fmt.Sprintf("%08x-1111-4222-8333-444455556666", i)] = testHeadA
+        }
+        restore := useMemoryHeads(t, logs)
+
+        seen := 0
+        cursor := ""
+        for p, want := range tc.pages {
+            target := fmt.Sprintf("/logs?limit=%d", tc.limit)
+            if cursor != "" {
+                target += "&cursor=" + cursor
+            }
+
+            var got listLogsResponse
+            if status := serve(t, "GET", target, "", &got); status != http.StatusOK {
+                t.Fatalf("%d logs by %d, page %d: got status %d", tc.logs, tc.limit, p, status)
+            }
+            if len(got.Logs) != want {
+                t.Errorf("%d logs by %d, page %d: got %d logs, want %d", tc.logs, tc.limit, p, len(got.Logs), want)
+            }
+            for i, l := range got.Logs {
+                if l.LogID != fmt.Sprintf("%08x-1111-4222-8333-444455556666", seen + i) {
+                    t.Errorf("%d logs by %d, page %d: got %s at %d", tc.logs, tc.limit, p, l.LogID, i)
+                }
+            }
+            seen += len(got.Logs)
+
+            last := p == len(tc.pages) - 1
+            if last && got.NextCursor != "" {
+                t.Errorf("%d logs by %d: got cursor %s on the last page", tc.logs, tc.limit, got.NextCursor)
+            }
+            if !last && got.NextCursor == "" {
+                t.Fatalf("%d logs by %d, page %d: no cursor", tc.logs, tc.limit, p)
+            }
+            cursor = got.NextCursor
+        }
+        restore()
+    }
+}
+
+func TestListLogsBadRequests(t *testing.T) {
+    restore := useMemoryHeads(t, nil)
+    defer restore()
+
+    cases := []string{
+        "/logs?limit=0",
+        "/logs?limit=1001",
+        "/logs?cursor=nope",
+        "/logs?updatedSince=yesterday",
+    }
+    for _, target := range cases {
+        if status := serve(t, "GET", target, "", nil); status != http.StatusBadRequest {
+            t.Errorf("%s: got status %d, want 400", target, status)
+        }
+    }
+}
+
+func TestListLogsUpdatedSinceUnsupported(t *testing.T) {
+    // The file store doesn't track when heads change.
+    prev := heads
+    heads = eventLog.NewFileHeads("testdata/missing.json")
+    defer func() { heads = prev }()
+
+    if status := serve(t, "GET", "/logs?updatedSince=2017-01-02T15:04:05Z", "", nil); status != http.StatusBadRequest {
+        t.Errorf("got status %d, want 400", status)
+    }
+    if status := serve(t, "GET", "/logs", "", nil); status != http.StatusOK {
+        t.Errorf("without the filter: got status %d, want 200", status)
+    }
+}
+
+func TestBatchGetLogsHandler(t *testing.T) {
+    restore := useMemoryHeads(t, map[string]string{
+        testLogA: testHeadA,
+        testLogB: testHeadB,
+    })
+    defer restore()
+
+    var got batchGetResponse
+    body := `{"logIds": ["` + testLogB + `", "` + testLogC + `", "` + testLogA + `"]}`
+    if status := serve(t, "POST", "/logs:batchGet", body, &got); status != http.StatusOK {
+        t.Fatalf("got status %d", status)
+    }
+
+    want := []struct {
+        logId string
+        head string
+    }{
+        {testLogB, testHeadB},
+        {testLogC, zeroHead},
+        {testLogA, testHeadA},
+    }
+    if len(got.Logs) != len(want) {
+        t.Fatalf("got %d logs, want %d", len(got.Logs), len(want))
+    }
+    for i, w := range want {
+        if got.Logs[i].LogID != w.logId || got.Logs[i].Head != w.head {
+            t.Errorf("logs[%d]: got %s %s, want %s %s", i, got.Logs[i].LogID, got.Logs[i].Head, w.logId, w.head)
+        }
+    }
+}
+
+func TestBatchGetLogsBadRequests(t *testing.T) {
+    restore := useMemoryHeads(t, nil)
+    defer restore()
+
+    tooMany := make([]string, MAX_BATCH_GET_LOGS + 1)
+    for i := range tooMany {
+        tooMany[i] = `"` + testLogA + `"`
+    }
+
+    cases := []struct {
+        name string
+        body string
+    }{
+        {"not JSON", `logIds`},
+        {"no logs", `{"logIds": []}`},
+        {"too many logs", `{"logIds": [` + strings.Join(tooMany, ",") + `]}`},
+        {"invalid log ID", `{"logIds": ["nope"]}`},
+        {"body too large", `{"logIds": ["` + testLogA + `"]` + strings.Repeat(" ", MAX_BATCH_GET_BYTES) + `}`},
+    }
+    for _, tc := range cases {
+        if status := serve(t, "POST", "/logs:batchGet", tc.body, nil); status != http.StatusBadRequest {
+            t.Errorf("%s: got status %d, want 400", tc.name, status)
+        }
+    }
+
+    // The largest allowed batch fits in the body limit.
+    var most []string
+    for i := 0; i < MAX_BATCH_GET_LOGS; i++ {
+        most = append(most, fmt.Sprintf(`"%08x-1111-4222-8333-444455556666"`, i))
+    }
+    var got batchGetResponse
+    body := `{"logIds": [` + strings.Join(most, ", ") + `]}`
+    if status := serve(t, "POST", "/logs:batchGet", body, &got); status != http.StatusOK {
+        t.Fatalf("full batch: got status %d", status)
+    }
+    if len(got.Logs) != MAX_BATCH_GET_LOGS {
+        t.Errorf("full batch: got %d logs", len(got.Logs))
+    }
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+const (
+    DEFAULT_PREFETCH_INTERVAL = time.Second
+    DEFAULT_PREFETCH_CONCURRENCY = 4
+    DEFAULT_PREFETCH_SCAN_INTERVAL = 5 * time.Minute
+    PREFETCH_QUEUE_SIZE = 1000
+    PREFETCH_PAGE_SIZE = 1000
+)
+
+// prefetcher polls log heads and loads new events into the caches ahead of readers.
+type prefetcher struct {
+    // heads returns the current head of every watched log.
+    heads func() (map[eventLog.LogID]event.EventID, error)
+    interval time.Duration
+    concurrency int
+    // limit paces prefetch jobs; nil means unlimited.
+    limit <-chan time.Time
+
+    mu sync.Mutex
+    known map[eventLog.LogID]event.EventID
+    pending map[eventLog.LogID]event.EventID
+    jobs chan eventLog.LogID
+}
+
+// PREFETCH selects which logs are kept warm: "off" (default), "all" logs in the head
+// store, or "list" for the comma-separated PREFETCH_LOG_IDS. PREFETCH_RATE caps the
+// number of logs refreshed per second.
+func newPrefetcher() (*prefetcher, error) {
+    p := &prefetcher{
+        known: make(map[eventLog.LogID]event.EventID),
+        pending: make(map[eventLog.LogID]event.EventID),
+    }
+
+    mode := os.Getenv("PREFETCH")
+    if mode == "" || mode == "off" {
+        return nil, nil
+    }
+
+    var err error
+    p.interval, err = envDuration("PREFETCH_INTERVAL", DEFAULT_PREFETCH_INTERVAL)
+    if err != nil {
+        return nil, err
+    }
+
+    switch mode {
+    case "all":
+        scanInterval, err := envDuration("PREFETCH_SCAN_INTERVAL", DEFAULT_PREFETCH_SCAN_INTERVAL)
+        if err != nil {
+            return nil, err
+        }
+        scanner := &headScanner{
+            scanInterval: scanInterval,
+            overlap: p.interval,
+            incremental: true,
+        }
+        p.heads = scanner.poll
+    case "list":
+        logIds, err := parseLogIds(os.Getenv("PREFETCH_LOG_IDS"))
+        if err != nil {
+            return nil, err
+        }
+        p.heads = func() (map[eventLog.LogID]event.EventID, error) {
+            return listedLogHeads(logIds)
+        }
+    default:
+        return nil, fmt.Errorf("unknown PREFETCH %q", mode)
+    }
+
+    concurrency, err := envInt64("PREFETCH_CONCURRENCY", DEFAULT_PREFETCH_CONCURRENCY)
+    if err != nil {
+        return nil, err
+    }
+    p.concurrency = int(concurrency)
+    if p.concurrency < 1 {
+        p.concurrency = 1
+    }
+
+    rate, err := envInt64("PREFETCH_RATE", 0)
+    if err != nil {
+        return nil, err
+    }
+    if rate > 0 {
+        p.limit = time.Tick(time.Second / time.Duration(rate))
+    }
+
+    return p, nil
+}
+
+func (p *prefetcher) run() {
+    p.jobs = make(chan eventLog.LogID, PREFETCH_QUEUE_SIZE)
+    for i := 0; i < p.concurrency; i++ {
+        go p.worker()
+    }
+
+    for range time.Tick(p.interval) {
+        heads, err := p.heads()
+        if err != nil {
+            logger.Println("Error polling log heads for prefetch.", err.Error())
+            continue
+        }
+
+        for logId, head := range heads {
+            p.enqueue(logId, head)
+        }
+    }
+}
+
+// enqueue schedules a log whose head has changed. A log already waiting for a worker
+// is not queued twice; the worker picks up the latest head. When the queue is full the
+// log is dropped and picked up again by a later poll.
+func (p *prefetcher) enqueue(logId eventLog.LogID, head event.EventID) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+
+    if p.known[logId] == head {
+        return
+    }
+    if _, queued := p.pending[logId]; queued {
+        p.pending[logId] = head
+        return
+    }
+
+    select {
+    case p.jobs <- logId:
+        p.pending[logId] = head
+    default:
+    }
+}
+
+func (p *prefetcher) worker() {
+    for logId := range p.jobs {
+        if p.limit != nil {
+            <-p.limit
+        }
+
+        p.mu.Lock()
+        head := p.pending[logId]
+        delete(p.pending, logId)
+        after := p.known[logId]
+        p.mu.Unlock()
+
+        // Only load the events since the head last prefetched, unless the log no
+        // longer descends from it.
+        _, err := getLogHistory(context.Background(), logId, head, after)
+        if err == errAfterNotInHistory {
+            _, err = getLogHistory(context.Background(), logId, head, event.EventID{})
+        }
+        if err != nil {
+            logger.Println("Error prefetching log.", logId.String(), err.Error())
+            continue
+        }
+
+        p.mu.Lock()
+        p.known[logId] = head
+        p.mu.Unlock()
+    }
+}
+
+// headScanner polls every log's head. Reading the whole store is expensive, so it
+// only does so every scanInterval. In between, it lists just the logs updated since
+// the previous poll, if the store tracks updates.
+type headScanner struct {
+    scanInterval time.Duration
+    // overlap widens each incremental poll to cover updates committed late.
+    overlap time.Duration
+    incremental bool
+
+    lastScan time.Time
+    lastPoll time.Time
+}
+
+func (s *headScanner) poll() (map[eventLog.LogID]event.EventID, error) {
+    now := time.Now()
+    if s.lastScan.IsZero() || now.Sub(s.lastScan) >= s.scanInterval {
+        out, err := allLogHeads()
+        if err != nil {
+            return nil, err
+        }
+        s.lastScan = now
+        s.lastPoll = now
+        return out, nil
+    }
+
+    if !s.incremental {
+        return map[eventLog.LogID]event.EventID{}, nil
+    }
+
+    out, err := updatedLogHeads(s.lastPoll.Add(-s.overlap))
+    if err == eventLog.ErrFilterUnsupported {
+        logger.Println("The log head store doesn't track updates; prefetch will rescan every", s.scanInterval.String())
+        s.incremental = false
+        return map[eventLog.LogID]event.EventID{}, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    s.lastPoll = now
+    return out, nil
+}
+
+func allLogHeads() (map[eventLog.LogID]event.EventID, error) {
+    return heads.Heads(context.Background())
+}
+
+// updatedLogHeads pages through the logs updated at or after since.
+func updatedLogHeads(since time.Time) (map[eventLog.LogID]event.EventID, error) {
+    out := make(map[eventLog.LogID]event.EventID)
+    opts := &eventLog.ListOptions{
+        Limit: PREFETCH_PAGE_SIZE,
+        UpdatedSince: since,
+    }
+    for {
+        page, err := heads.List(context.Background(), opts)
+        if err != nil {
+            return nil, err
+        }
+        for _, l := range page {
+            out[l.ID] = l.Head
+        }
+        if len(page) < opts.Limit {
+            return out, nil
+        }
+        opts.After = &page[len(page) - 1].ID
+    }
+}
+
+// listedLogHeads returns the heads of the given logs. Logs with no record are
+// included with the zero head, as empty logs.
+func listedLogHeads(logIds []eventLog.LogID) (map[eventLog.LogID]event.EventID, error) {
+    out, err := heads.HeadsOf(context.Background(), logIds)
+    if err != nil {
+        return nil, err
+    }
+    for _, logId := range logIds {
+        if _, ok := out[logId]; !ok {
+            out[logId] = event.EventID{}
+        }
+    }
+    return out, nil
+}
+
+func parseLogIds(s string) ([]eventLog.LogID, error) {
+    out := make([]eventLog.LogID, 0)
+    for _, part := range strings.Split(s, ",") {
+        part = strings.TrimSpace(part)
+        if part == "" {
+            continue
+        }
+
+        var logId eventLog.LogID
+        err := logId.Parse(part)
+        if err != nil {
+            return nil, err
+        }
+        out = append(out, logId)
+    }
+    return out, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+    "testing"
+
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+func TestListedLogHeadsIncludesUnknownLogs(t *testing.T) {
+    restore := useMemoryHeads(t, map[string]string{
+        testLogA: testHeadA,
+    })
+    defer restore()
+
+    var logA, logB eventLog.LogID
+    logA.Parse(testLogA)
+    logB.Parse(testLogB)
+
+    got, err := listedLogHeads([]eventLog.LogID{logA, logB})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(got) != 2 {
+        t.Fatalf("got %d heads, want 2", len(got))
+    }
+    if head := got[logA]; head.String() != testHeadA {
+        t.Errorf("got head %s for the known log", head.String())
+    }
+    if head, ok := got[logB]; !ok || head != (event.EventID{}) {
+        t.Errorf("got head %s for the unknown log, want the zero head", head.String())
+    }
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+    "encoding/base64"
+    "net/http"
+
+    "github.com/gorilla/mux"
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+type proofResponse struct {
+    LogID string `json:"logId"`
+    Head string `json:"head"`
+    EventID string `json:"eventId"`
+    Steps []*proofStepJson `json:"steps"`
+}
+
+type proofStepJson struct {
+    Type string `json:"type"`
+    Data string `json:"data"`
+}
+
+func proofHandler(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    logId := eventLog.LogID{}
+    err := logId.Parse(vars["logId"])
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    eventParam := r.URL.Query().Get("event")
+    if eventParam == "" {
+        http.Error(w, "Must supply event in query.", http.StatusBadRequest)
+        return
+    }
+    eventId := event.EventID{}
+    err = eventId.Parse(eventParam)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    if eventId == (event.EventID{}) {
+        http.Error(w, "The event is not in the log's history.", http.StatusNotFound)
+        return
+    }
+
+    ctx := r.Context()
+    headEventId, err := getLogHead(ctx, logId)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+
+    events, err := getLogHistory(ctx, logId, headEventId, eventId)
+    if err == errAfterNotInHistory {
+        http.Error(w, "The event is not in the log's history.", http.StatusNotFound)
+        return
+    } else if err != nil {
+        writeError(w, err)
+        return
+    }
+
+    // The history is newest first; proofs run from the event towards the head.
+    l := len(events)
+    oldestFirst := make([]*event.Event, l)
+    for i, e := range events {
+        oldestFirst[l - 1 - i] = e
+    }
+    proof := eventLog.NewProof(eventId, oldestFirst)
+
+    steps := make([]*proofStepJson, len(proof.Steps))
+    for i, step := range proof.Steps {
+        steps[i] = &proofStepJson{
+            Type: step.Type,
+            Data: base64.StdEncoding.EncodeToString(step.Data),
+        }
+    }
+
+    writeJson(w, &proofResponse{
+        LogID: logId.String(),
+        Head: headEventId.String(),
+        EventID: eventId.String(),
+        Steps: steps,
+    })
+}
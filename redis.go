@@ -0,0 +1,144 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/go-redis/redis"
+)
+
+// Redis is a cache, so operations get tight timeouts and no retries: a slow Redis
+// should fall through to the next tier rather than stall a history walk.
+const (
+    DEFAULT_REDIS_DIAL_TIMEOUT = 250 * time.Millisecond
+    DEFAULT_REDIS_OP_TIMEOUT = 100 * time.Millisecond
+)
+
+type redisTimeouts struct {
+    dial time.Duration
+    read time.Duration
+    write time.Duration
+    pool time.Duration
+}
+
+func loadRedisTimeouts() (*redisTimeouts, error) {
+    dial, err := envDuration("REDIS_DIAL_TIMEOUT", DEFAULT_REDIS_DIAL_TIMEOUT)
+    if err != nil {
+        return nil, err
+    }
+    op, err := envDuration("REDIS_OP_TIMEOUT", DEFAULT_REDIS_OP_TIMEOUT)
+    if err != nil {
+        return nil, err
+    }
+
+    return &redisTimeouts{
+        dial: dial,
+        read: op,
+        write: op,
+        pool: op,
+    }, nil
+}
+
+// REDIS_MODE selects the deployment the Redis tier connects to:
+//   single   - one server at REDIS_ADDRS, or REDIS_HOSTNAME:REDIS_PORT (default)
+//   sentinel - a failover client for REDIS_MASTER_NAME, discovered via the sentinels in REDIS_ADDRS
+//   cluster  - a Redis Cluster, seeded from REDIS_ADDRS
+//   ring     - client-side consistent hashing across the shards in REDIS_ADDRS
+// REDIS_ADDRS is a comma-separated list of host:port. Ring shards may be named as
+// name=host:port; unnamed shards are named after their address.
+func newRedisClient() (redis.UniversalClient, error) {
+    password := os.Getenv("REDIS_PASSWORD")
+    db, err := envInt64("REDIS_DB", 0)
+    if err != nil {
+        return nil, err
+    }
+
+    timeouts, err := loadRedisTimeouts()
+    if err != nil {
+        return nil, err
+    }
+
+    addrs := redisAddrs()
+
+    mode := os.Getenv("REDIS_MODE")
+    switch mode {
+    case "", "single":
+        if len(addrs) != 1 {
+            return nil, fmt.Errorf("REDIS_MODE %q requires exactly one address", "single")
+        }
+        return redis.NewClient(&redis.Options{
+            Addr: addrs[0],
+            Password: password,
+            DB: int(db),
+            DialTimeout: timeouts.dial,
+            ReadTimeout: timeouts.read,
+            WriteTimeout: timeouts.write,
+            PoolTimeout: timeouts.pool,
+        }), nil
+    case "sentinel":
+        masterName := os.Getenv("REDIS_MASTER_NAME")
+        if masterName == "" {
+            return nil, fmt.Errorf("REDIS_MODE %q requires REDIS_MASTER_NAME", mode)
+        }
+        return redis.NewFailoverClient(&redis.FailoverOptions{
+            MasterName: masterName,
+            SentinelAddrs: addrs,
+            Password: password,
+            DB: int(db),
+            DialTimeout: timeouts.dial,
+            ReadTimeout: timeouts.read,
+            WriteTimeout: timeouts.write,
+            PoolTimeout: timeouts.pool,
+        }), nil
+    case "cluster":
+        if db != 0 {
+            return nil, fmt.Errorf("REDIS_MODE %q does not support REDIS_DB", mode)
+        }
+        return redis.NewClusterClient(&redis.ClusterOptions{
+            Addrs: addrs,
+            Password: password,
+            DialTimeout: timeouts.dial,
+            ReadTimeout: timeouts.read,
+            WriteTimeout: timeouts.write,
+            PoolTimeout: timeouts.pool,
+        }), nil
+    case "ring":
+        shards := make(map[string]string, len(addrs))
+        for _, addr := range addrs {
+            name := addr
+            if i := strings.Index(addr, "="); i >= 0 {
+                name, addr = addr[:i], addr[i+1:]
+            }
+            shards[name] = addr
+        }
+        return redis.NewRing(&redis.RingOptions{
+            Addrs: shards,
+            Password: password,
+            DB: int(db),
+            DialTimeout: timeouts.dial,
+            ReadTimeout: timeouts.read,
+            WriteTimeout: timeouts.write,
+            PoolTimeout: timeouts.pool,
+        }), nil
+    }
+
+    return nil, fmt.Errorf("unknown REDIS_MODE %q", mode)
+}
+
+func redisAddrs() []string {
+    s := os.Getenv("REDIS_ADDRS")
+    if s == "" {
+        return []string{fmt.Sprintf("%s:%s", os.Getenv("REDIS_HOSTNAME"), os.Getenv("REDIS_PORT"))}
+    }
+
+    out := make([]string, 0)
+    for _, addr := range strings.Split(s, ",") {
+        addr = strings.TrimSpace(addr)
+        if addr != "" {
+            out = append(out, addr)
+        }
+    }
+    return out
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+    "crypto/ed25519"
+    "crypto/sha256"
+    "crypto/x509"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/pem"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "time"
+
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+var (
+    // signer is nil unless SIGNING_KEY_FILE is set.
+    signer *headSigner
+)
+
+// headSigner signs the heads reported by readLogHandler.
+type headSigner struct {
+    key ed25519.PrivateKey
+    keyId string
+}
+
+type signingKeyResponse struct {
+    Algorithm string `json:"algorithm"`
+    KeyID string `json:"keyId"`
+    // PublicKey is the raw 32-byte key, base64 encoded.
+    PublicKey string `json:"publicKey"`
+    PEM string `json:"pem"`
+}
+
+// loadSigner reads an Ed25519 private key from a PKCS #8 PEM file, as written by
+// `openssl genpkey -algorithm ed25519`.
+func loadSigner(path string) (*headSigner, error) {
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    block, _ := pem.Decode(b)
+    if block == nil || block.Type != "PRIVATE KEY" {
+        return nil, fmt.Errorf("%s is not a PEM encoded PKCS #8 private key", path)
+    }
+    parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+    if err != nil {
+        return nil, err
+    }
+    key, ok := parsed.(ed25519.PrivateKey)
+    if !ok {
+        return nil, fmt.Errorf("%s is not an Ed25519 key", path)
+    }
+
+    // Identifies the key so that clients can tell when it has been rotated.
+    pub := key.Public().(ed25519.PublicKey)
+    sum := sha256.Sum256(pub)
+
+    return &headSigner{
+        key: key,
+        keyId: hex.EncodeToString(sum[:8]),
+    }, nil
+}
+
+// sign fills in the timestamp and signature of the response.
+func (s *headSigner) sign(resp *readLogResponse, logId eventLog.LogID, head event.EventID) {
+    now := time.Now().UTC()
+    resp.Timestamp = now.Format(time.RFC3339Nano)
+    resp.KeyID = s.keyId
+    resp.Signature = base64.StdEncoding.EncodeToString(eventLog.SignHead(s.key, logId, head, now))
+}
+
+func signingKeyHandler(w http.ResponseWriter, r *http.Request) {
+    if signer == nil {
+        http.Error(w, "Responses are not signed.", http.StatusNotFound)
+        return
+    }
+
+    pub := signer.key.Public().(ed25519.PublicKey)
+    der, err := x509.MarshalPKIXPublicKey(pub)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    writeJson(w, &signingKeyResponse{
+        Algorithm: "Ed25519",
+        KeyID: signer.keyId,
+        PublicKey: base64.StdEncoding.EncodeToString(pub),
+        PEM: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+    })
+}
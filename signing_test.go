@@ -0,0 +1,75 @@
+package main
+
+import (
+    "crypto/ed25519"
+    "encoding/base64"
+    "encoding/hex"
+    "testing"
+    "time"
+
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// testdata/signing_key.pem holds the Ed25519 key with the seed 00 01 02 ... 1f.
+const (
+    testSigningKeyID = "56475aa75463474c"
+    testSigningPublicKey = "03a107bff3ce10be1d70dd18e74bc09967e4d6309ba50d5f1ddc8664125531b8"
+)
+
+func TestSignedHeadVerifies(t *testing.T) {
+    s, err := loadSigner("testdata/signing_key.pem")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if s.keyId != testSigningKeyID {
+        t.Errorf("got key ID %s, want %s", s.keyId, testSigningKeyID)
+    }
+    pub := s.key.Public().(ed25519.PublicKey)
+    if hex.EncodeToString(pub) != testSigningPublicKey {
+        t.Fatalf("got public key %x", pub)
+    }
+
+    var logId eventLog.LogID
+    var head event.EventID
+    logId.Parse(testLogA)
+    head.Parse(testHeadA)
+    resp := &readLogResponse{
+        LogID: testLogA,
+        Head: testHeadA,
+    }
+    s.sign(resp, logId, head)
+
+    if resp.KeyID != testSigningKeyID {
+        t.Errorf("got response key ID %s", resp.KeyID)
+    }
+    // Clients only have the timestamp text, so it must parse back to the signed time.
+    at, err := time.Parse(time.RFC3339Nano, resp.Timestamp)
+    if err != nil {
+        t.Fatal(err)
+    }
+    sig, err := base64.StdEncoding.DecodeString(resp.Signature)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !eventLog.VerifyHead(pub, logId, head, at, sig) {
+        t.Errorf("signature doesn't verify at %s", resp.Timestamp)
+    }
+
+    var other event.EventID
+    other.Parse(testHeadB)
+    if eventLog.VerifyHead(pub, logId, other, at, sig) {
+        t.Errorf("signature verifies for another head")
+    }
+    if eventLog.VerifyHead(pub, logId, head, at.Add(time.Nanosecond), sig) {
+        t.Errorf("signature verifies at another time")
+    }
+}
+
+func TestLoadSignerRejectsOtherFiles(t *testing.T) {
+    for _, path := range []string{"testdata/missing.pem", "heads_test.go"} {
+        if _, err := loadSigner(path); err == nil {
+            t.Errorf("%s: loaded a signer", path)
+        }
+    }
+}
@@ -0,0 +1,51 @@
+package source
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net"
+)
+
+var (
+    // ErrNotFound means the source has no event with the requested ID.
+    ErrNotFound = errors.New("event not found upstream")
+    // ErrUnavailable means the source is being skipped after repeated failures.
+    ErrUnavailable = errors.New("upstream event source is unavailable")
+)
+
+// StatusError is an unexpected HTTP status from an upstream API.
+type StatusError struct {
+    Code int
+}
+
+func (e *StatusError) Error() string {
+    return fmt.Sprintf("upstream responded with status %d", e.Code)
+}
+
+// Temporary reports whether the request may succeed if retried.
+func (e *StatusError) Temporary() bool {
+    return e.Code == 429 || e.Code >= 500
+}
+
+// ResponseError is an upstream response that could not be decoded.
+type ResponseError struct {
+    Err error
+}
+
+func (e *ResponseError) Error() string {
+    return "invalid upstream response: " + e.Err.Error()
+}
+
+// IsTransient reports whether err is a failure that may succeed on retry, such as a
+// network error, a timeout or a 5xx response. The caller's own cancellation is not
+// transient.
+func IsTransient(err error) bool {
+    switch err := err.(type) {
+    case *StatusError:
+        return err.Temporary()
+    case net.Error:
+        return true
+    }
+    return err == context.DeadlineExceeded
+}
@@ -0,0 +1,61 @@
+package source
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// FileSource reads events from a directory with one file per event, named by its hex
+// ID and holding the event's JSON as stored by the event store. It is meant for local
+// development and tests.
+type FileSource struct {
+    dir string
+}
+
+func NewFile(dir string) (*FileSource, error) {
+    info, err := os.Stat(dir)
+    if err != nil {
+        return nil, err
+    }
+    if !info.IsDir() {
+        return nil, &os.PathError{Op: "open", Path: dir, Err: os.ErrInvalid}
+    }
+
+    return &FileSource{
+        dir: dir,
+    }, nil
+}
+
+func (s *FileSource) GetEvent(ctx context.Context, id event.EventID) (*event.Event, error) {
+    if ctx.Err() != nil {
+        return nil, ctx.Err()
+    }
+
+    f, err := os.Open(filepath.Join(s.dir, id.String()))
+    if os.IsNotExist(err) {
+        return nil, ErrNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    e, err := decodeStoredEvent(f)
+    if err != nil {
+        return nil, &ResponseError{Err: err}
+    }
+    return e, nil
+}
+
+// Name identifies the source by its directory.
+func (s *FileSource) Name() string {
+    return "file://" + s.dir
+}
+
+// Healthy always reports true; there is nothing to fail over from.
+func (s *FileSource) Healthy() bool {
+    return true
+}
@@ -0,0 +1,145 @@
+package source
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "time"
+
+    "github.com/tobyjsullivan/event-log-reader/breaker"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+const (
+    DEFAULT_HTTP_RETRIES = 2
+    DEFAULT_HTTP_MAX_IDLE_CONNS = 64
+    DEFAULT_BREAKER_THRESHOLD = 10
+    DEFAULT_BREAKER_COOLDOWN = 5 * time.Second
+
+    retryBaseDelay = 50 * time.Millisecond
+    retryMaxDelay = time.Second
+)
+
+// HTTPSource reads events from the upstream event reader API. Transient failures are
+// retried with jittered backoff, and a circuit breaker stops calling an upstream that
+// keeps failing until its cooldown has passed.
+type HTTPSource struct {
+    apiBase *url.URL
+    client *http.Client
+    timeout time.Duration
+    retry *retrier
+}
+
+type HTTPConfig struct {
+    ServiceUrl string
+    // Timeout bounds each attempt, on top of the caller's context.
+    Timeout time.Duration
+    // Retries is how many times a transient failure is retried.
+    Retries int
+    // MaxIdleConns is the size of the keep-alive connection pool.
+    MaxIdleConns int
+    // BreakerThreshold is how many consecutive transient failures open the breaker.
+    BreakerThreshold int
+    // BreakerCooldown is how long the breaker stays open before a trial request.
+    BreakerCooldown time.Duration
+}
+
+func NewHTTP(conf *HTTPConfig) (*HTTPSource, error) {
+    api, err := url.Parse(conf.ServiceUrl)
+    if err != nil {
+        return nil, err
+    }
+
+    maxIdle := conf.MaxIdleConns
+    if maxIdle <= 0 {
+        maxIdle = DEFAULT_HTTP_MAX_IDLE_CONNS
+    }
+
+    return &HTTPSource{
+        apiBase: api,
+        client: &http.Client{
+            Transport: newTransport(maxIdle),
+        },
+        timeout: conf.Timeout,
+        retry: newRetrier(conf.Retries, conf.BreakerThreshold, conf.BreakerCooldown),
+    }, nil
+}
+
+func (s *HTTPSource) GetEvent(ctx context.Context, id event.EventID) (*event.Event, error) {
+    return s.retry.do(ctx, func() (*event.Event, error) {
+        return s.get(ctx, id)
+    })
+}
+
+// Name identifies the upstream by its URL.
+func (s *HTTPSource) Name() string {
+    return s.apiBase.String()
+}
+
+// Healthy reports whether the upstream is currently being called.
+func (s *HTTPSource) Healthy() bool {
+    return s.retry.breaker.State() != breaker.Open
+}
+
+func (s *HTTPSource) get(ctx context.Context, id event.EventID) (*event.Event, error) {
+    if s.timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, s.timeout)
+        defer cancel()
+    }
+
+    endpointUrl := s.apiBase.ResolveReference(&url.URL{Path: "./events/" + id.String()})
+    req, err := http.NewRequest("GET", endpointUrl.String(), nil)
+    if err != nil {
+        return nil, err
+    }
+
+    resp, err := s.client.Do(req.WithContext(ctx))
+    if err != nil {
+        if ctx.Err() != nil {
+            return nil, ctx.Err()
+        }
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    switch {
+    case resp.StatusCode == http.StatusNotFound:
+        return nil, ErrNotFound
+    case resp.StatusCode != http.StatusOK:
+        return nil, &StatusError{Code: resp.StatusCode}
+    }
+
+    var parsedResp eventReaderResp
+    err = json.NewDecoder(resp.Body).Decode(&parsedResp)
+    if err != nil {
+        if ctx.Err() != nil {
+            return nil, ctx.Err()
+        }
+        return nil, &ResponseError{Err: err}
+    }
+
+    prevId := event.EventID{}
+    err = prevId.Parse(parsedResp.Previous)
+    if err != nil {
+        return nil, &ResponseError{Err: err}
+    }
+
+    parsedData, err := event.ParseData(parsedResp.Data)
+    if err != nil {
+        return nil, &ResponseError{Err: err}
+    }
+
+    return &event.Event{
+        PreviousEvent: prevId,
+        Type: parsedResp.Type,
+        Data: parsedData,
+    }, nil
+}
+
+type eventReaderResp struct {
+    Previous string `json:"previous"`
+    Type string `json:"type"`
+    Data string `json:"data"`
+}
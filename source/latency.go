@@ -0,0 +1,60 @@
+package source
+
+import (
+    "sort"
+    "sync"
+    "time"
+)
+
+// latencyTracker keeps a sliding window of recent call latencies. Percentiles are
+// recomputed every few samples rather than on every lookup.
+type latencyTracker struct {
+    mu sync.Mutex
+    samples []time.Duration
+    next int
+    sinceSort int
+    sorted []time.Duration
+}
+
+const (
+    latencyWindow = 1000
+    latencyResortEvery = 50
+)
+
+func (t *latencyTracker) add(d time.Duration) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    if len(t.samples) < latencyWindow {
+        t.samples = append(t.samples, d)
+    } else {
+        t.samples[t.next] = d
+        t.next = (t.next + 1) % latencyWindow
+    }
+
+    t.sinceSort++
+    if t.sorted == nil || t.sinceSort >= latencyResortEvery {
+        t.sorted = append(t.sorted[:0], t.samples...)
+        sort.Slice(t.sorted, func(i, j int) bool { return t.sorted[i] < t.sorted[j] })
+        t.sinceSort = 0
+    }
+}
+
+// percentile returns the p-th percentile (0-100) of the window, or false if there
+// are too few samples to be meaningful.
+func (t *latencyTracker) percentile(p float64) (time.Duration, bool) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    if len(t.sorted) < latencyResortEvery {
+        return 0, false
+    }
+
+    if p < 0 {
+        p = 0
+    } else if p > 100 {
+        p = 100
+    }
+    i := int(float64(len(t.sorted) - 1) * p / 100)
+    return t.sorted[i], true
+}
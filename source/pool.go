@@ -0,0 +1,219 @@
+package source
+
+import (
+    "context"
+    "sync/atomic"
+    "time"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// Endpoint is one upstream in a Pool.
+type Endpoint interface {
+    EventSource
+    Name() string
+    Healthy() bool
+}
+
+// Pool spreads reads across several replicas of the upstream. Requests rotate across
+// healthy replicas and fail over to the next one on transient errors. With hedging
+// enabled, a second request goes to another replica if the first hasn't answered
+// within the configured latency percentile, and the first answer wins.
+//
+// The pool does the retrying, so its endpoints should be built without retries of
+// their own; otherwise a hung replica uses up all of its retries before the next
+// replica is tried.
+type Pool struct {
+    endpoints []Endpoint
+    next uint32
+    retries int
+    hedgePercentile float64
+    hedgeDelay time.Duration
+    latencies latencyTracker
+    hedges uint64
+}
+
+type PoolConfig struct {
+    // Retries is how many times a transient failure is retried across the pool, each
+    // time on the next replica. Hedged requests count as retries.
+    Retries int
+    // HedgePercentile (0-100) sets the hedging delay from recent latencies. Zero
+    // disables hedging.
+    HedgePercentile float64
+    // HedgeDelay is the delay used until enough latencies have been observed, and
+    // the lower bound on the percentile-based delay.
+    HedgeDelay time.Duration
+}
+
+type EndpointStats struct {
+    Name string `json:"name"`
+    Healthy bool `json:"healthy"`
+}
+
+type PoolStats struct {
+    Endpoints []EndpointStats `json:"endpoints"`
+    Hedges uint64 `json:"hedges"`
+    HedgeDelay string `json:"hedgeDelay,omitempty"`
+}
+
+func NewPool(conf *PoolConfig, endpoints ...Endpoint) *Pool {
+    return &Pool{
+        endpoints: endpoints,
+        retries: conf.Retries,
+        hedgePercentile: conf.HedgePercentile,
+        hedgeDelay: conf.HedgeDelay,
+    }
+}
+
+func (p *Pool) GetEvent(ctx context.Context, id event.EventID) (*event.Event, error) {
+    order := p.order()
+    if p.hedgePercentile > 0 && len(order) > 1 {
+        return p.hedged(ctx, id, order)
+    }
+    return p.failover(ctx, id, order, p.retries + 1)
+}
+
+func (p *Pool) Stats() PoolStats {
+    out := PoolStats{
+        Endpoints: make([]EndpointStats, len(p.endpoints)),
+        Hedges: atomic.LoadUint64(&p.hedges),
+    }
+    for i, e := range p.endpoints {
+        out.Endpoints[i] = EndpointStats{
+            Name: e.Name(),
+            Healthy: e.Healthy(),
+        }
+    }
+    if p.hedgePercentile > 0 {
+        out.HedgeDelay = p.currentHedgeDelay().String()
+    }
+    return out
+}
+
+// order returns the endpoints to try, healthy ones first, rotating the starting
+// point so load is spread across replicas.
+func (p *Pool) order() []Endpoint {
+    n := len(p.endpoints)
+    start := int(atomic.AddUint32(&p.next, 1)) % n
+
+    healthy := make([]Endpoint, 0, n)
+    unhealthy := make([]Endpoint, 0)
+    for i := 0; i < n; i++ {
+        e := p.endpoints[(start + i) % n]
+        if e.Healthy() {
+            healthy = append(healthy, e)
+        } else {
+            unhealthy = append(unhealthy, e)
+        }
+    }
+    return append(healthy, unhealthy...)
+}
+
+// failover makes up to attempts requests, each to the next of the endpoints, until
+// one answers. Errors that another replica would repeat, such as ErrNotFound, are
+// returned straight away. Once every endpoint has been tried it goes round them
+// again, backing off first.
+func (p *Pool) failover(ctx context.Context, id event.EventID, endpoints []Endpoint, attempts int) (*event.Event, error) {
+    var lastErr error = ErrUnavailable
+    if len(endpoints) == 0 {
+        return nil, lastErr
+    }
+    for i := 0; i < attempts; i++ {
+        if i >= len(endpoints) {
+            select {
+            case <-time.After(backoff(i / len(endpoints) - 1)):
+            case <-ctx.Done():
+                return nil, ctx.Err()
+            }
+        }
+
+        e := endpoints[i % len(endpoints)]
+        start := time.Now()
+        ev, err := e.GetEvent(ctx, id)
+        // Failed attempts, and hedged ones cut short by the winner, count too, or the
+        // window would only see the fastest answers.
+        p.latencies.add(time.Since(start))
+        if err == nil {
+            return ev, nil
+        }
+        if ctx.Err() != nil {
+            return nil, ctx.Err()
+        }
+        if !retryable(err) {
+            return nil, err
+        }
+        lastErr = err
+    }
+    return nil, lastErr
+}
+
+type hedgeResult struct {
+    e *event.Event
+    err error
+}
+
+func (p *Pool) hedged(ctx context.Context, id event.EventID, order []Endpoint) (*event.Event, error) {
+    // Cancels whichever request is still running once there is an answer.
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    // The first replica gets one attempt and the hedge the rest, so hedging doesn't
+    // add to the attempts a read can make.
+    results := make(chan hedgeResult, 2)
+    run := func(endpoints []Endpoint, attempts int) {
+        e, err := p.failover(ctx, id, endpoints, attempts)
+        results <- hedgeResult{e: e, err: err}
+    }
+
+    go run(order[:1], 1)
+    pending := 1
+    hedged := false
+    hedge := func() {
+        hedged = true
+        pending++
+        go run(order[1:], p.retries)
+    }
+
+    timer := time.NewTimer(p.currentHedgeDelay())
+    defer timer.Stop()
+
+    for {
+        select {
+        case <-timer.C:
+            if !hedged {
+                atomic.AddUint64(&p.hedges, 1)
+                hedge()
+            }
+        case r := <-results:
+            pending--
+            if r.err == nil {
+                return r.e, nil
+            }
+            if !retryable(r.err) {
+                return nil, r.err
+            }
+            if !hedged {
+                // No point waiting for the timer once the first replica has failed.
+                hedge()
+                continue
+            }
+            if pending == 0 {
+                return nil, r.err
+            }
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        }
+    }
+}
+
+func (p *Pool) currentHedgeDelay() time.Duration {
+    d, ok := p.latencies.percentile(p.hedgePercentile)
+    if !ok || d < p.hedgeDelay {
+        return p.hedgeDelay
+    }
+    return d
+}
+
+func retryable(err error) bool {
+    return err == ErrUnavailable || IsTransient(err)
+}
@@ -0,0 +1,101 @@
+package source
+
+import (
+    "context"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// testEndpoint answers every read with err, or with an event if err is nil.
+type testEndpoint struct {
+    name string
+    err error
+    calls int32
+}
+
+func (e *testEndpoint) GetEvent(ctx context.Context, id event.EventID) (*event.Event, error) {
+    atomic.AddInt32(&e.calls, 1)
+    if e.err != nil {
+        return nil, e.err
+    }
+    return &event.Event{Type: e.name}, nil
+}
+
+func (e *testEndpoint) Name() string {
+    return e.name
+}
+
+func (e *testEndpoint) Healthy() bool {
+    return true
+}
+
+func TestPoolRetriesAcrossReplicas(t *testing.T) {
+    cases := []struct {
+        name string
+        retries int
+        hedge float64
+        replicas int
+        wantCalls int32
+    }{
+        {"one attempt per replica", 2, 0, 3, 3},
+        {"retries fewer than replicas", 1, 0, 3, 2},
+        {"no retries", 0, 0, 3, 1},
+        {"more retries than replicas", 3, 0, 2, 4},
+        {"hedged", 2, 50, 3, 3},
+    }
+
+    for _, tc := range cases {
+        var endpoints []Endpoint
+        var replicas []*testEndpoint
+        for i := 0; i < tc.replicas; i++ {
+            e := &testEndpoint{name: "replica", err: &StatusError{Code: 503}}
+            endpoints = append(endpoints, e)
+            replicas = append(replicas, e)
+        }
+        p := NewPool(&PoolConfig{
+            Retries: tc.retries,
+            HedgePercentile: tc.hedge,
+            HedgeDelay: time.Millisecond,
+        }, endpoints...)
+
+        _, err := p.GetEvent(context.Background(), event.EventID{})
+        if !IsTransient(err) {
+            t.Errorf("%s: got error %v, want the last transient failure", tc.name, err)
+        }
+
+        var calls int32
+        for _, e := range replicas {
+            calls += atomic.LoadInt32(&e.calls)
+        }
+        if calls != tc.wantCalls {
+            t.Errorf("%s: got %d attempts, want %d", tc.name, calls, tc.wantCalls)
+        }
+    }
+}
+
+func TestPoolFailsOver(t *testing.T) {
+    down := &testEndpoint{name: "down", err: &StatusError{Code: 502}}
+    up := &testEndpoint{name: "up"}
+    p := NewPool(&PoolConfig{Retries: 1}, down, up)
+
+    // Each read starts on a different replica, so both orders are covered.
+    for i := 0; i < 2; i++ {
+        e, err := p.GetEvent(context.Background(), event.EventID{})
+        if err != nil {
+            t.Fatal(err)
+        }
+        if e.Type != "up" {
+            t.Errorf("got event from %s", e.Type)
+        }
+    }
+
+    // A missing event would be missing from every replica.
+    missing := &testEndpoint{name: "missing", err: ErrNotFound}
+    p = NewPool(&PoolConfig{Retries: 1}, missing, &testEndpoint{name: "missing", err: ErrNotFound})
+    if _, err := p.GetEvent(context.Background(), event.EventID{}); err != ErrNotFound {
+        t.Errorf("got error %v, want ErrNotFound", err)
+    }
+}
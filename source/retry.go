@@ -0,0 +1,87 @@
+package source
+
+import (
+    "context"
+    "math/rand"
+    "net"
+    "net/http"
+    "time"
+
+    "github.com/tobyjsullivan/event-log-reader/breaker"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// retrier retries transient failures with jittered backoff behind a circuit breaker.
+type retrier struct {
+    retries int
+    breaker *breaker.Breaker
+}
+
+func newRetrier(retries int, threshold int, cooldown time.Duration) *retrier {
+    if threshold <= 0 {
+        threshold = DEFAULT_BREAKER_THRESHOLD
+    }
+    if cooldown <= 0 {
+        cooldown = DEFAULT_BREAKER_COOLDOWN
+    }
+
+    return &retrier{
+        retries: retries,
+        breaker: breaker.New(threshold, cooldown),
+    }
+}
+
+func (r *retrier) do(ctx context.Context, get func() (*event.Event, error)) (*event.Event, error) {
+    for attempt := 0; ; attempt++ {
+        if !r.breaker.Allow() {
+            return nil, ErrUnavailable
+        }
+
+        e, err := get()
+        if err != nil && ctx.Err() != nil {
+            return nil, ctx.Err()
+        }
+        if err == nil || !IsTransient(err) {
+            // The upstream answered, even if it was with a 404 or a bad response.
+            r.breaker.Success()
+            return e, err
+        }
+        r.breaker.Failure()
+
+        if attempt >= r.retries {
+            return nil, err
+        }
+
+        select {
+        case <-time.After(backoff(attempt)):
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        }
+    }
+}
+
+// backoff returns a random delay up to an exponentially growing cap ("full jitter").
+func backoff(attempt int) time.Duration {
+    max := retryBaseDelay << uint(attempt)
+    if max > retryMaxDelay || max <= 0 {
+        max = retryMaxDelay
+    }
+    return time.Duration(rand.Int63n(int64(max)))
+}
+
+// newTransport returns a transport for a client that talks to a single host, so it
+// keeps as many idle connections as the pool allows rather than the default two.
+func newTransport(maxIdle int) *http.Transport {
+    return &http.Transport{
+        Proxy: http.ProxyFromEnvironment,
+        DialContext: (&net.Dialer{
+            Timeout: 2 * time.Second,
+            KeepAlive: 30 * time.Second,
+        }).DialContext,
+        MaxIdleConns: maxIdle,
+        MaxIdleConnsPerHost: maxIdle,
+        IdleConnTimeout: 90 * time.Second,
+        TLSHandshakeTimeout: 2 * time.Second,
+        ExpectContinueTimeout: time.Second,
+    }
+}
@@ -0,0 +1,177 @@
+package source
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/tobyjsullivan/event-log-reader/breaker"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// S3Source reads event objects straight from an S3-compatible bucket, skipping the
+// upstream event reader. Each event is an object keyed by KeyPrefix, the lowercase hex
+// event ID and KeySuffix, holding the event's JSON as written by event.Event's String
+// (see decodeStoredEvent). Retries and the circuit breaker work as in HTTPSource.
+type S3Source struct {
+    endpoint *url.URL
+    bucket string
+    region string
+    keyPrefix string
+    keySuffix string
+    pathStyle bool
+    creds *credentials
+    client *http.Client
+    timeout time.Duration
+    retry *retrier
+}
+
+type S3Config struct {
+    Bucket string
+    Region string
+    // Endpoint is the base URL of an S3-compatible API such as MinIO. When empty,
+    // AWS's regional endpoint is used.
+    Endpoint string
+    // KeyPrefix and KeySuffix are put around the hex event ID to make an object key,
+    // e.g. "events/" and ".json".
+    KeyPrefix string
+    KeySuffix string
+    // AccessKeyID and SecretAccessKey sign requests. Requests are sent unsigned when
+    // they are empty.
+    AccessKeyID string
+    SecretAccessKey string
+    SessionToken string
+    Timeout time.Duration
+    Retries int
+    MaxIdleConns int
+    BreakerThreshold int
+    BreakerCooldown time.Duration
+}
+
+func NewS3(conf *S3Config) (*S3Source, error) {
+    if conf.Bucket == "" {
+        return nil, fmt.Errorf("no S3 bucket configured")
+    }
+    if conf.Region == "" {
+        return nil, fmt.Errorf("no AWS region configured")
+    }
+
+    // AWS is addressed with virtual-hosted URLs. Other S3-compatible stores usually
+    // serve a single host, so the bucket goes in the path.
+    pathStyle := conf.Endpoint != ""
+    endpoint := conf.Endpoint
+    if endpoint == "" {
+        endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", conf.Region)
+    }
+    u, err := url.Parse(endpoint)
+    if err != nil {
+        return nil, err
+    }
+    if u.Scheme == "" || u.Host == "" {
+        return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+    }
+
+    var creds *credentials
+    if conf.AccessKeyID != "" || conf.SecretAccessKey != "" {
+        creds = &credentials{
+            AccessKeyID: conf.AccessKeyID,
+            SecretAccessKey: conf.SecretAccessKey,
+            SessionToken: conf.SessionToken,
+        }
+    }
+
+    maxIdle := conf.MaxIdleConns
+    if maxIdle <= 0 {
+        maxIdle = DEFAULT_HTTP_MAX_IDLE_CONNS
+    }
+
+    return &S3Source{
+        endpoint: u,
+        bucket: conf.Bucket,
+        region: conf.Region,
+        keyPrefix: conf.KeyPrefix,
+        keySuffix: conf.KeySuffix,
+        pathStyle: pathStyle,
+        creds: creds,
+        client: &http.Client{
+            Transport: newTransport(maxIdle),
+        },
+        timeout: conf.Timeout,
+        retry: newRetrier(conf.Retries, conf.BreakerThreshold, conf.BreakerCooldown),
+    }, nil
+}
+
+func (s *S3Source) GetEvent(ctx context.Context, id event.EventID) (*event.Event, error) {
+    return s.retry.do(ctx, func() (*event.Event, error) {
+        return s.get(ctx, id)
+    })
+}
+
+// Name identifies the source by its bucket.
+func (s *S3Source) Name() string {
+    return "s3://" + s.bucket + "/" + s.keyPrefix
+}
+
+// Healthy reports whether the bucket is currently being called.
+func (s *S3Source) Healthy() bool {
+    return s.retry.breaker.State() != breaker.Open
+}
+
+func (s *S3Source) objectUrl(id event.EventID) *url.URL {
+    key := s.keyPrefix + id.String() + s.keySuffix
+
+    u := *s.endpoint
+    if s.pathStyle {
+        u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+    } else {
+        u.Host = s.bucket + "." + u.Host
+        u.Path = "/" + key
+    }
+    return &u
+}
+
+func (s *S3Source) get(ctx context.Context, id event.EventID) (*event.Event, error) {
+    if s.timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, s.timeout)
+        defer cancel()
+    }
+
+    req, err := http.NewRequest("GET", s.objectUrl(id).String(), nil)
+    if err != nil {
+        return nil, err
+    }
+    if s.creds != nil {
+        signV4(req, s.creds, s.region, "s3", time.Now())
+    }
+
+    resp, err := s.client.Do(req.WithContext(ctx))
+    if err != nil {
+        if ctx.Err() != nil {
+            return nil, ctx.Err()
+        }
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    // A missing key is a 404 only when the credentials may list the bucket; otherwise
+    // S3 answers 403, which is left as an error rather than hiding a bad policy.
+    switch {
+    case resp.StatusCode == http.StatusNotFound:
+        return nil, ErrNotFound
+    case resp.StatusCode != http.StatusOK:
+        return nil, &StatusError{Code: resp.StatusCode}
+    }
+
+    e, err := decodeStoredEvent(resp.Body)
+    if err != nil {
+        if ctx.Err() != nil {
+            return nil, ctx.Err()
+        }
+        return nil, &ResponseError{Err: err}
+    }
+    return e, nil
+}
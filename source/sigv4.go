@@ -0,0 +1,169 @@
+package source
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+)
+
+// AWS Signature Version 4, as described in
+// https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html. Only what is
+// needed to sign bodiless GET requests is implemented.
+
+const (
+    sigV4Algorithm = "AWS4-HMAC-SHA256"
+    sigV4TimeFormat = "20060102T150405Z"
+    sigV4DateFormat = "20060102"
+
+    // The SHA-256 of an empty body.
+    emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+type credentials struct {
+    AccessKeyID string
+    SecretAccessKey string
+    SessionToken string
+}
+
+// signV4 adds the x-amz-* headers and the Authorization header to req.
+func signV4(req *http.Request, creds *credentials, region string, service string, now time.Time) {
+    req.Header.Set("X-Amz-Date", now.UTC().Format(sigV4TimeFormat))
+    req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+    if creds.SessionToken != "" {
+        req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+    }
+
+    req.Header.Set("Authorization", authorization(req, creds, region, service, now, emptyPayloadHash))
+}
+
+// authorization signs the host and x-amz-* headers already set on req.
+func authorization(req *http.Request, creds *credentials, region string, service string, now time.Time, payloadHash string) string {
+    canonical, signedHeaders := canonicalRequest(req, payloadHash)
+    scope := credentialScope(now, region, service)
+    signature := hex.EncodeToString(hmacSha256(signingKey(creds, now, region, service), stringToSign(now, scope, canonical)))
+
+    return sigV4Algorithm +
+        " Credential=" + creds.AccessKeyID + "/" + scope +
+        ", SignedHeaders=" + signedHeaders +
+        ", Signature=" + signature
+}
+
+// canonicalRequest returns the canonical form of req and the list of headers it
+// covers.
+func canonicalRequest(req *http.Request, payloadHash string) (string, string) {
+    headers := map[string]string{
+        "host": req.URL.Host,
+    }
+    for name, values := range req.Header {
+        name = strings.ToLower(name)
+        if strings.HasPrefix(name, "x-amz-") {
+            headers[name] = strings.TrimSpace(strings.Join(values, ","))
+        }
+    }
+    names := make([]string, 0, len(headers))
+    for name := range headers {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    var canonicalHeaders strings.Builder
+    for _, name := range names {
+        canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+    }
+    signedHeaders := strings.Join(names, ";")
+
+    return strings.Join([]string{
+        req.Method,
+        uriEncodePath(req.URL.Path),
+        canonicalQuery(req),
+        canonicalHeaders.String(),
+        signedHeaders,
+        payloadHash,
+    }, "\n"), signedHeaders
+}
+
+func credentialScope(now time.Time, region string, service string) string {
+    return strings.Join([]string{now.UTC().Format(sigV4DateFormat), region, service, "aws4_request"}, "/")
+}
+
+func stringToSign(now time.Time, scope string, canonicalRequest string) string {
+    return strings.Join([]string{
+        sigV4Algorithm,
+        now.UTC().Format(sigV4TimeFormat),
+        scope,
+        hexSha256(canonicalRequest),
+    }, "\n")
+}
+
+func signingKey(creds *credentials, now time.Time, region string, service string) []byte {
+    key := hmacSha256([]byte("AWS4" + creds.SecretAccessKey), now.UTC().Format(sigV4DateFormat))
+    key = hmacSha256(key, region)
+    key = hmacSha256(key, service)
+    return hmacSha256(key, "aws4_request")
+}
+
+func canonicalQuery(req *http.Request) string {
+    query := req.URL.Query()
+    keys := make([]string, 0, len(query))
+    for k := range query {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+
+    parts := make([]string, 0, len(keys))
+    for _, k := range keys {
+        values := query[k]
+        sort.Strings(values)
+        for _, v := range values {
+            parts = append(parts, uriEncode(k, true) + "=" + uriEncode(v, true))
+        }
+    }
+    return strings.Join(parts, "&")
+}
+
+// uriEncodePath encodes each segment of an object path. S3 expects paths to be encoded
+// once, unlike other services.
+func uriEncodePath(path string) string {
+    if path == "" {
+        return "/"
+    }
+    return uriEncode(path, false)
+}
+
+// uriEncode percent-encodes everything but the RFC 3986 unreserved characters, and
+// '/' unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+    const hexDigits = "0123456789ABCDEF"
+
+    var b strings.Builder
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        switch {
+        case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+            c == '-', c == '_', c == '.', c == '~':
+            b.WriteByte(c)
+        case c == '/' && !encodeSlash:
+            b.WriteByte(c)
+        default:
+            b.WriteByte('%')
+            b.WriteByte(hexDigits[c >> 4])
+            b.WriteByte(hexDigits[c & 15])
+        }
+    }
+    return b.String()
+}
+
+func hmacSha256(key []byte, data string) []byte {
+    h := hmac.New(sha256.New, key)
+    h.Write([]byte(data))
+    return h.Sum(nil)
+}
+
+func hexSha256(data string) string {
+    sum := sha256.Sum256([]byte(data))
+    return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,47 @@
+package source
+
+import (
+    "context"
+    "encoding/json"
+    "io"
+
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+// EventSource is the origin of events, such as the upstream event reader API.
+type EventSource interface {
+    GetEvent(ctx context.Context, id event.EventID) (*event.Event, error)
+}
+
+// storedEvent is the form events are stored in by the event store, as written by
+// event.Event's String.
+type storedEvent struct {
+    PreviousID string `json:"previousId"`
+    Type string `json:"type"`
+    Data string `json:"data"`
+}
+
+func decodeStoredEvent(r io.Reader) (*event.Event, error) {
+    var stored storedEvent
+    err := json.NewDecoder(r).Decode(&stored)
+    if err != nil {
+        return nil, err
+    }
+
+    prevId := event.EventID{}
+    err = prevId.Parse(stored.PreviousID)
+    if err != nil {
+        return nil, err
+    }
+
+    data, err := event.ParseData(stored.Data)
+    if err != nil {
+        return nil, err
+    }
+
+    return &event.Event{
+        PreviousEvent: prevId,
+        Type: stored.Type,
+        Data: data,
+    }, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "strings"
+
+    "github.com/tobyjsullivan/event-log-reader/cache"
+)
+
+const (
+    DEFAULT_CACHE_TIERS = "memory,redis"
+    DEFAULT_DISK_CACHE_DIR = "data/event-cache"
+    DEFAULT_DISK_CACHE_MAX_BYTES = 1 << 30
+    DEFAULT_REDIS_FAILURE_THRESHOLD = 5
+)
+
+// cacheTiers maps the names accepted in CACHE_TIERS to their constructors. Register
+// new tiers here; "none" is handled by buildCacheTiers and configures no tiers.
+var cacheTiers = map[string]func() (cache.Tier, error){
+    "memory": newMemoryTier,
+    "redis": newRedisTier,
+    "disk": newDiskTier,
+}
+
+// buildCacheTiers parses a comma-separated list of tier names, fastest first.
+func buildCacheTiers(spec string) ([]cache.Tier, error) {
+    if spec == "" {
+        spec = DEFAULT_CACHE_TIERS
+    }
+
+    tiers := make([]cache.Tier, 0)
+    for _, name := range strings.Split(spec, ",") {
+        name = strings.TrimSpace(name)
+        if name == "none" {
+            continue
+        }
+
+        build, ok := cacheTiers[name]
+        if !ok {
+            return nil, fmt.Errorf("unknown cache tier %q", name)
+        }
+
+        t, err := build()
+        if err != nil {
+            return nil, err
+        }
+        tiers = append(tiers, t)
+    }
+
+    return tiers, nil
+}
+
+func newMemoryTier() (cache.Tier, error) {
+    return cache.New(CACHE_MAX_KEYS), nil
+}
+
+func newRedisTier() (cache.Tier, error) {
+    client, err := newRedisClient()
+    if err != nil {
+        return nil, err
+    }
+
+    ttl, err := envDuration("REDIS_TTL", 0)
+    if err != nil {
+        return nil, err
+    }
+
+    threshold, err := envInt64("REDIS_FAILURE_THRESHOLD", DEFAULT_REDIS_FAILURE_THRESHOLD)
+    if err != nil {
+        return nil, err
+    }
+
+    probeInterval, err := envDuration("REDIS_PROBE_INTERVAL", cache.DEFAULT_PROBE_INTERVAL)
+    if err != nil {
+        return nil, err
+    }
+
+    return cache.NewRedisTier(client, &cache.RedisTierOptions{
+        KeyPrefix: os.Getenv("REDIS_KEY_PREFIX"),
+        TTL: ttl,
+        FailureThreshold: int(threshold),
+        ProbeInterval: probeInterval,
+    }, logger), nil
+}
+
+func newDiskTier() (cache.Tier, error) {
+    dir := os.Getenv("CACHE_DISK_DIR")
+    if dir == "" {
+        dir = DEFAULT_DISK_CACHE_DIR
+    }
+
+    maxBytes, err := envInt64("CACHE_DISK_MAX_BYTES", DEFAULT_DISK_CACHE_MAX_BYTES)
+    if err != nil {
+        return nil, err
+    }
+
+    return cache.NewDiskTier(dir, maxBytes, logger)
+}
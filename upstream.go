@@ -0,0 +1,115 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/tobyjsullivan/event-log-reader/source"
+)
+
+const (
+    DEFAULT_EVENT_SOURCE = "http"
+    DEFAULT_UPSTREAM_HEDGE_DELAY = 50 * time.Millisecond
+    DEFAULT_EVENT_DIR = "data/events"
+)
+
+// eventSources maps the names accepted in EVENT_SOURCE to their constructors. Each is
+// given the client settings shared by the network sources, which the file source
+// ignores.
+var eventSources = map[string]func(conf source.HTTPConfig) (source.EventSource, error){
+    "http": newHTTPSource,
+    "s3": newS3Source,
+    "file": newFileSource,
+}
+
+func buildEventSource(name string, conf source.HTTPConfig) (source.EventSource, error) {
+    if name == "" {
+        name = DEFAULT_EVENT_SOURCE
+    }
+
+    build, ok := eventSources[name]
+    if !ok {
+        return nil, fmt.Errorf("unknown event source %q", name)
+    }
+    return build(conf)
+}
+
+// newHTTPSource creates a client for each URL in EVENT_READER_API. More than one URL
+// is served by a pool that fails over between them and optionally hedges. The pool
+// retries on the next replica, so its clients don't retry themselves.
+func newHTTPSource(conf source.HTTPConfig) (source.EventSource, error) {
+    var urls []string
+    for _, u := range strings.Split(os.Getenv("EVENT_READER_API"), ",") {
+        u = strings.TrimSpace(u)
+        if u != "" {
+            urls = append(urls, u)
+        }
+    }
+
+    switch len(urls) {
+    case 0:
+        // Keeps the previous behaviour of an unset EVENT_READER_API.
+        return source.NewHTTP(&conf)
+    case 1:
+        conf.ServiceUrl = urls[0]
+        return source.NewHTTP(&conf)
+    }
+
+    retries := conf.Retries
+    conf.Retries = 0
+    var endpoints []source.Endpoint
+    for _, u := range urls {
+        conf.ServiceUrl = u
+        s, err := source.NewHTTP(&conf)
+        if err != nil {
+            return nil, err
+        }
+        endpoints = append(endpoints, s)
+    }
+
+    percentile, err := envInt64("UPSTREAM_HEDGE_PERCENTILE", 0)
+    if err != nil {
+        return nil, err
+    }
+    if percentile < 0 || percentile > 100 {
+        return nil, fmt.Errorf("UPSTREAM_HEDGE_PERCENTILE must be between 0 and 100, got %d", percentile)
+    }
+    delay, err := envDuration("UPSTREAM_HEDGE_DELAY", DEFAULT_UPSTREAM_HEDGE_DELAY)
+    if err != nil {
+        return nil, err
+    }
+
+    return source.NewPool(&source.PoolConfig{
+        Retries: retries,
+        HedgePercentile: float64(percentile),
+        HedgeDelay: delay,
+    }, endpoints...), nil
+}
+
+func newS3Source(conf source.HTTPConfig) (source.EventSource, error) {
+    return source.NewS3(&source.S3Config{
+        Bucket: os.Getenv("S3_BUCKET"),
+        Region: os.Getenv("AWS_REGION"),
+        Endpoint: os.Getenv("S3_ENDPOINT"),
+        KeyPrefix: os.Getenv("S3_KEY_PREFIX"),
+        KeySuffix: os.Getenv("S3_KEY_SUFFIX"),
+        AccessKeyID: os.Getenv("AWS_ACCESS_KEY_ID"),
+        SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+        SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+        Timeout: conf.Timeout,
+        Retries: conf.Retries,
+        MaxIdleConns: conf.MaxIdleConns,
+        BreakerThreshold: conf.BreakerThreshold,
+        BreakerCooldown: conf.BreakerCooldown,
+    })
+}
+
+func newFileSource(conf source.HTTPConfig) (source.EventSource, error) {
+    dir := os.Getenv("EVENT_DIR")
+    if dir == "" {
+        dir = DEFAULT_EVENT_DIR
+    }
+    return source.NewFile(dir)
+}
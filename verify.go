@@ -0,0 +1,217 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "os"
+    "sync"
+    "time"
+
+    "github.com/gorilla/mux"
+    "github.com/tobyjsullivan/event-log-reader/cache"
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+    "github.com/tobyjsullivan/event-log-reader/source"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+const (
+    PROBLEM_MISSING = "missing"
+    PROBLEM_MISMATCH = "mismatch"
+    PROBLEM_CYCLE = "cycle"
+)
+
+var (
+    verifyJob *verifier
+)
+
+type verifyReport struct {
+    LogID string `json:"logId"`
+    Head string `json:"head"`
+    Intact bool `json:"intact"`
+    // Length is the number of events checked. When the log is intact, the event at
+    // depth d is at position Length - d.
+    Length uint64 `json:"length"`
+    Problems []*chainProblem `json:"problems"`
+    CheckedAt time.Time `json:"checkedAt"`
+}
+
+type chainProblem struct {
+    Kind string `json:"kind"`
+    EventID string `json:"eventId"`
+    // Depth counts back from the head, which is at depth 0.
+    Depth uint64 `json:"depth"`
+    // ReferencedBy is the event whose previous event pointer led here.
+    ReferencedBy string `json:"referencedBy,omitempty"`
+    Detail string `json:"detail"`
+}
+
+// verifyLog walks the log from head to genesis, checking that every event hashes to
+// the ID its successor points at. The walk stops at the first broken link, since the
+// rest of the chain can't be found from there. Failures to read the chain, such as
+// an unavailable upstream, are returned as errors rather than problems.
+func verifyLog(ctx context.Context, logId eventLog.LogID, head event.EventID) (*verifyReport, error) {
+    zero := event.EventID{}
+    report := &verifyReport{
+        LogID: logId.String(),
+        Head: head.String(),
+        Problems: make([]*chainProblem, 0),
+    }
+
+    seen := make(map[event.EventID]uint64)
+    from := zero
+    next, err := walkChain(ctx, head, zero, func(id event.EventID, e *event.Event) bool {
+        if d, ok := seen[id]; ok {
+            report.Problems = append(report.Problems, &chainProblem{
+                Kind: PROBLEM_CYCLE,
+                EventID: id.String(),
+                Depth: report.Length,
+                ReferencedBy: from.String(),
+                Detail: fmt.Sprintf("already seen at depth %d", d),
+            })
+            return false
+        }
+        seen[id] = report.Length
+        report.Length++
+        from = id
+        return true
+    })
+    if err != nil {
+        p := problemFor(err)
+        if p == nil {
+            return nil, err
+        }
+        p.EventID = next.String()
+        p.Depth = report.Length
+        if from != zero {
+            p.ReferencedBy = from.String()
+        }
+        report.Problems = append(report.Problems, p)
+    }
+
+    report.Intact = len(report.Problems) == 0
+    report.CheckedAt = time.Now().UTC()
+    return report, nil
+}
+
+func problemFor(err error) *chainProblem {
+    if err == source.ErrNotFound {
+        return &chainProblem{
+            Kind: PROBLEM_MISSING,
+            Detail: err.Error(),
+        }
+    }
+    if _, ok := err.(*cache.MismatchError); ok {
+        return &chainProblem{
+            Kind: PROBLEM_MISMATCH,
+            Detail: err.Error(),
+        }
+    }
+    return nil
+}
+
+func verifyLogHandler(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    logId := eventLog.LogID{}
+    err := logId.Parse(vars["logId"])
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    ctx := r.Context()
+    headEventId, err := getLogHead(ctx, logId)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+
+    report, err := verifyLog(ctx, logId, headEventId)
+    if err != nil {
+        writeError(w, err)
+        return
+    }
+
+    writeJson(w, report)
+}
+
+// verifier periodically verifies every log in the head store, or the logs in
+// VERIFY_LOG_IDS, and keeps the latest report for each.
+type verifier struct {
+    interval time.Duration
+    logIds []eventLog.LogID
+
+    mu sync.Mutex
+    reports map[eventLog.LogID]*verifyReport
+}
+
+// VERIFY_INTERVAL enables the job; it is off by default.
+func newVerifier() (*verifier, error) {
+    interval, err := envDuration("VERIFY_INTERVAL", 0)
+    if err != nil {
+        return nil, err
+    }
+    if interval <= 0 {
+        return nil, nil
+    }
+
+    logIds, err := parseLogIds(os.Getenv("VERIFY_LOG_IDS"))
+    if err != nil {
+        return nil, err
+    }
+
+    return &verifier{
+        interval: interval,
+        logIds: logIds,
+        reports: make(map[eventLog.LogID]*verifyReport),
+    }, nil
+}
+
+func (v *verifier) run() {
+    for {
+        v.verifyAll()
+        time.Sleep(v.interval)
+    }
+}
+
+func (v *verifier) verifyAll() {
+    ctx := context.Background()
+
+    var logHeads map[eventLog.LogID]event.EventID
+    var err error
+    if len(v.logIds) > 0 {
+        logHeads, err = listedLogHeads(v.logIds)
+    } else {
+        logHeads, err = heads.Heads(ctx)
+    }
+    if err != nil {
+        logger.Println("Error reading log heads for verification.", err.Error())
+        return
+    }
+
+    for logId, head := range logHeads {
+        report, err := verifyLog(ctx, logId, head)
+        if err != nil {
+            logger.Println("Error verifying log.", logId.String(), err.Error())
+            continue
+        }
+        for _, p := range report.Problems {
+            logger.Println("Log failed verification.", logId.String(), p.Kind, p.EventID, "at depth", p.Depth)
+        }
+
+        v.mu.Lock()
+        v.reports[logId] = report
+        v.mu.Unlock()
+    }
+}
+
+func (v *verifier) Reports() []*verifyReport {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+
+    out := make([]*verifyReport, 0, len(v.reports))
+    for _, r := range v.reports {
+        out = append(out, r)
+    }
+    return out
+}
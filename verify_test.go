@@ -0,0 +1,81 @@
+package main
+
+import (
+    "context"
+    "testing"
+
+    eventLog "github.com/tobyjsullivan/event-log-reader/log"
+    "github.com/tobyjsullivan/ues-sdk/event"
+)
+
+func TestVerifyLog(t *testing.T) {
+    events := testEvents(event.EventID{}, "Test", 5)
+    ids := make([]event.EventID, len(events))
+    for i, e := range events {
+        ids[i] = e.ID()
+    }
+    head := ids[4]
+
+    intact := newTestSource(events...)
+    missing := newTestSource(events...)
+    delete(missing, ids[2])
+    mismatch := newTestSource(events...)
+    mismatch[ids[1]] = events[0]
+    missingHead := newTestSource(events...)
+    delete(missingHead, head)
+
+    cases := []struct {
+        name string
+        src testSource
+        length uint64
+        kind string
+        eventId event.EventID
+        depth uint64
+        referencedBy event.EventID
+    }{
+        {"intact", intact, 5, "", event.EventID{}, 0, event.EventID{}},
+        {"missing", missing, 2, PROBLEM_MISSING, ids[2], 2, ids[3]},
+        {"mismatch", mismatch, 3, PROBLEM_MISMATCH, ids[1], 3, ids[2]},
+        {"missing head", missingHead, 0, PROBLEM_MISSING, head, 0, event.EventID{}},
+    }
+
+    var logId eventLog.LogID
+    logId.Parse(testLogA)
+    for _, tc := range cases {
+        // Read the chain one event at a time and in batches, once its links are known.
+        restore := useSource(tc.src)
+        for _, pass := range []string{"unindexed", "indexed"} {
+            report, err := verifyLog(context.Background(), logId, head)
+            if err != nil {
+                t.Fatalf("%s, %s: %s", tc.name, pass, err.Error())
+            }
+            if report.Length != tc.length {
+                t.Errorf("%s, %s: got length %d, want %d", tc.name, pass, report.Length, tc.length)
+            }
+            if tc.kind == "" {
+                if !report.Intact || len(report.Problems) != 0 {
+                    t.Errorf("%s, %s: got %d problems", tc.name, pass, len(report.Problems))
+                }
+                continue
+            }
+
+            if report.Intact || len(report.Problems) != 1 {
+                t.Errorf("%s, %s: got %d problems, want 1", tc.name, pass, len(report.Problems))
+                continue
+            }
+            p := report.Problems[0]
+            if p.Kind != tc.kind || p.EventID != tc.eventId.String() || p.Depth != tc.depth {
+                t.Errorf("%s, %s: got %s of %s at depth %d, want %s of %s at depth %d", tc.name, pass,
+                    p.Kind, p.EventID, p.Depth, tc.kind, tc.eventId.String(), tc.depth)
+            }
+            wantRef := ""
+            if tc.referencedBy != (event.EventID{}) {
+                wantRef = tc.referencedBy.String()
+            }
+            if p.ReferencedBy != wantRef {
+                t.Errorf("%s, %s: got referenced by %q, want %q", tc.name, pass, p.ReferencedBy, wantRef)
+            }
+        }
+        restore()
+    }
+}